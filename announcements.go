@@ -0,0 +1,165 @@
+package ortc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+//AnnouncementType is the kind of event an announcement reports.
+type AnnouncementType int
+
+const (
+	//AnnouncementConnected reports a client that connected.
+	AnnouncementConnected AnnouncementType = iota
+	//AnnouncementDisconnected reports a client that disconnected.
+	AnnouncementDisconnected
+	//AnnouncementSubscribed reports a client that subscribed a channel.
+	AnnouncementSubscribed
+	//AnnouncementUnsubscribed reports a client that unsubscribed a channel.
+	AnnouncementUnsubscribed
+)
+
+var announcementChannels = map[AnnouncementType]string{
+	AnnouncementConnected:    "ortcClientConnected",
+	AnnouncementDisconnected: "ortcClientDisconnected",
+	AnnouncementSubscribed:   "ortcClientSubscribed",
+	AnnouncementUnsubscribed: "ortcClientUnsubscribed",
+}
+
+func (t AnnouncementType) String() string {
+	if name, ok := announcementChannels[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("AnnouncementType(%d)", int(t))
+}
+
+//Announcement is a connection or subscription event of a client using the same announcement sub-channel,
+//delivered by SubscribeAnnouncements.
+type Announcement struct {
+	Sender *OrtcClient      `json:"-"`
+	Type   AnnouncementType `json:"-"`
+	//Metadata is the connection metadata of the announced client.
+	Metadata string `json:"cm"`
+	//IP is the address of the announced client.
+	IP string `json:"ip"`
+	//Channel is the channel subscribed or unsubscribed. It is empty for connections and disconnections.
+	Channel string `json:"ch"`
+}
+
+//SetAnnouncementSubChannel sets the announcement sub-channel sent to the server when connecting, so the
+//connections and subscriptions of the client are announced on it. The setting applies from the next connection.
+func (c *OrtcClient) SetAnnouncementSubChannel(subChannel string) error {
+	if !ortcIsValidInput(subChannel) {
+		return ortcInvalidCharactersException("Announcement Subchannel")
+	} else if len(announcementChannel(AnnouncementUnsubscribed, subChannel)) > max_channel_size {
+		return ortcMaxLengthException("Announcement Subchannel", max_channel_size-len(announcementChannels[AnnouncementUnsubscribed])-1)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.announcementSubChannel = subChannel
+	return nil
+}
+
+//GetAnnouncementSubChannel returns the announcement sub-channel of the client.
+func (c *OrtcClient) GetAnnouncementSubChannel() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.announcementSubChannel
+}
+
+//announcementChannel returns the channel of the announcements of type t on subChannel.
+func announcementChannel(t AnnouncementType, subChannel string) string {
+	if len(subChannel) == 0 {
+		return announcementChannels[t]
+	}
+	return announcementChannels[t] + ":" + subChannel
+}
+
+//SubscribeAnnouncements subscribes the announcement channels of the announcement sub-channel of the client
+//and blocks until the server confirms every subscription. The announcements are decoded and delivered on
+//the returned channel, which is closed once the announcement channels are unsubscribed or the client disconnects.
+//The announcements follow the overflow policy and buffer size of the subscriptions.
+//The authentication token needs read permission on the ortcClientConnected, ortcClientDisconnected,
+//ortcClientSubscribed and ortcClientUnsubscribed channels.
+func (c *OrtcClient) SubscribeAnnouncements(ctx context.Context, subscribeOnReconnect bool) (<-chan Announcement, error) {
+	subChannel := c.GetAnnouncementSubChannel()
+
+	types := []AnnouncementType{AnnouncementConnected, AnnouncementDisconnected, AnnouncementSubscribed, AnnouncementUnsubscribed}
+	subscriptions := make([]<-chan onMessageChannel, 0, len(types))
+	for _, t := range types {
+		onMessage, err := c.SubscribeContext(ctx, announcementChannel(t, subChannel), subscribeOnReconnect)
+		if err != nil {
+			for _, subscribed := range types[:len(subscriptions)] {
+				c.startUnsubscribe(announcementChannel(subscribed, subChannel), false)
+			}
+			return nil, err
+		}
+		subscriptions = append(subscriptions, onMessage)
+	}
+
+	announcements := make(chan Announcement, c.opts.streamBufferSize(StreamSubscription))
+	var wg sync.WaitGroup
+	for i, onMessage := range subscriptions {
+		wg.Add(1)
+		go func(t AnnouncementType, onMessage <-chan onMessageChannel, done <-chan struct{}) {
+			defer wg.Done()
+			for msg := range onMessage {
+				announcement := Announcement{Sender: c, Type: t}
+				if err := json.Unmarshal([]byte(msg.Message), &announcement); err != nil {
+					raiseOrtcExceptionEvent(onException, c, ortcInvalidMessageException(fmt.Sprintf("Invalid announcement on channel %s: %v", msg.Channel, err)))
+					continue
+				}
+				deliverAnnouncement(c, announcements, announcement, done)
+			}
+		}(types[i], onMessage, c.subscriptionDone(announcementChannel(types[i], subChannel), onMessage))
+	}
+	go func() {
+		wg.Wait()
+		close(announcements)
+	}()
+	return announcements, nil
+}
+
+//deliverAnnouncement writes announcement to announcements following the subscription overflow policy.
+//A blocked write gives up once done is closed, so the decoder goroutines end after the announcement
+//channels are unsubscribed even if nobody reads the announcements anymore.
+func deliverAnnouncement(c *OrtcClient, announcements chan Announcement, announcement Announcement, done <-chan struct{}) {
+	deliver(c, c.opts.subscriptionPolicy(), StreamSubscription, func(block bool) bool {
+		if block {
+			select {
+			case announcements <- announcement:
+				return true
+			case <-done:
+				return false
+			}
+		}
+		select {
+		case announcements <- announcement:
+			return true
+		default:
+			return false
+		}
+	}, func() bool {
+		select {
+		case <-announcements:
+			return true
+		default:
+			return false
+		}
+	})
+}
+
+//subscriptionDone returns a channel closed once the subscription of channel delivering on onMessage is closed.
+func (c *OrtcClient) subscriptionDone(channel string, onMessage <-chan onMessageChannel) <-chan struct{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if subscription := c.subscribedChannels[channel]; subscription != nil && (<-chan onMessageChannel)(subscription.onMessage) == onMessage {
+		return subscription.done
+	}
+	//The subscription was already replaced or removed, so it is closed.
+	done := make(chan struct{})
+	close(done)
+	return done
+}
@@ -0,0 +1,91 @@
+package ortc
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+//announce broadcasts an announcement, given as JSON, on the announcement channel of type t.
+func announce(fs *fakeServer, t AnnouncementType, subChannel, announcement string) {
+	fs.broadcast(announcementChannel(t, subChannel), "abcdefgh_1-1_"+strings.Replace(announcement, `"`, `\\\"`, -1))
+}
+
+func TestSubscribeAnnouncements(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	fs := newFakeServer(t)
+	c := NewClient(WithEventHandler(HandlerFuncs{}))
+	if err := c.SetAnnouncementSubChannel("sub"); err != nil {
+		t.Fatalf("SetAnnouncementSubChannel: %v", err)
+	}
+	if err := c.ConnectContext(ctx, "appKey", "token", "", fs.URL, false, false); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	announcements, err := c.SubscribeAnnouncements(ctx, false)
+	if err != nil {
+		t.Fatalf("SubscribeAnnouncements: %v", err)
+	}
+
+	want := []Announcement{
+		{c, AnnouncementConnected, "meta1", "10.0.0.1", ""},
+		{c, AnnouncementSubscribed, "meta1", "10.0.0.1", "news"},
+		{c, AnnouncementUnsubscribed, "meta1", "10.0.0.1", "news"},
+		{c, AnnouncementDisconnected, "meta1", "10.0.0.1", ""},
+	}
+	for _, w := range want {
+		if w.Channel == "" {
+			announce(fs, w.Type, "sub", `{"cm":"meta1","ip":"10.0.0.1"}`)
+		} else {
+			announce(fs, w.Type, "sub", `{"cm":"meta1","ip":"10.0.0.1","ch":"news"}`)
+		}
+		select {
+		case got := <-announcements:
+			if got != w {
+				t.Fatalf("announcement = %+v, want %+v", got, w)
+			}
+		case <-ctx.Done():
+			t.Fatalf("%v announcement not delivered", w.Type)
+		}
+	}
+
+	c.Disconnect()
+	select {
+	case _, ok := <-announcements:
+		if ok {
+			t.Fatal("announcement delivered after disconnecting")
+		}
+	case <-ctx.Done():
+		t.Fatal("announcements not closed after disconnecting")
+	}
+}
+
+func TestAnnouncementsOverflow(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	fs := newFakeServer(t)
+	c := connectClient(t, ctx, fs, WithOverflowPolicy(OverflowDropNewest), WithStreamBufferSize(StreamSubscription, 1))
+	announcements, err := c.SubscribeAnnouncements(ctx, false)
+	if err != nil {
+		t.Fatalf("SubscribeAnnouncements: %v", err)
+	}
+
+	//Nobody reads the announcements: the second one is dropped instead of blocking its decoder.
+	announce(fs, AnnouncementConnected, "", `{"cm":"first","ip":"10.0.0.1"}`)
+	announce(fs, AnnouncementConnected, "", `{"cm":"second","ip":"10.0.0.1"}`)
+	waitFor(t, "the announcement to be dropped", func() bool { return c.DroppedEvents(StreamSubscription) == 1 })
+	if got := <-announcements; got.Metadata != "first" {
+		t.Fatalf("announcement = %+v, want the first one", got)
+	}
+
+	c.Disconnect()
+	select {
+	case _, ok := <-announcements:
+		if ok {
+			t.Fatal("announcement delivered after disconnecting")
+		}
+	case <-ctx.Done():
+		t.Fatal("announcements not closed after disconnecting")
+	}
+}
@@ -3,16 +3,37 @@ package authentication
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
 )
 
+//requestTimeout bounds the requests of SaveAuthentication.
+const requestTimeout = 15 * time.Second
+
 func SaveAuthentication(authenticationUrl *url.URL, authenticationToken string, authenticationTokenIsPrivate bool,
 	applicationKey string, timeToLive int, privateKey string, permissions map[string][]ChannelPermissions) (bool, error) {
 
+	return SaveAuthenticationWithClient(&http.Client{Timeout: requestTimeout}, authenticationUrl, authenticationToken, authenticationTokenIsPrivate,
+		applicationKey, timeToLive, privateKey, permissions)
+}
+
+//SaveAuthenticationWithClient works like SaveAuthentication, sending the request with httpClient.
+func SaveAuthenticationWithClient(httpClient *http.Client, authenticationUrl *url.URL, authenticationToken string, authenticationTokenIsPrivate bool,
+	applicationKey string, timeToLive int, privateKey string, permissions map[string][]ChannelPermissions) (bool, error) {
+
+	return SaveAuthenticationWithContext(context.Background(), httpClient, authenticationUrl, authenticationToken, authenticationTokenIsPrivate,
+		applicationKey, timeToLive, privateKey, permissions)
+}
+
+//SaveAuthenticationWithContext works like SaveAuthenticationWithClient, abandoning the request when ctx is done.
+func SaveAuthenticationWithContext(ctx context.Context, httpClient *http.Client, authenticationUrl *url.URL, authenticationToken string,
+	authenticationTokenIsPrivate bool, applicationKey string, timeToLive int, privateKey string, permissions map[string][]ChannelPermissions) (bool, error) {
+
 	strAuthenticationTokenIsPrivate := ""
 
 	if authenticationTokenIsPrivate {
@@ -34,24 +55,25 @@ func SaveAuthentication(authenticationUrl *url.URL, authenticationToken string,
 		postBody = fmt.Sprintf("%s%s", postBody, chPermission)
 	}
 
-	isAuthenticated, err := postRequest(authenticationUrl, postBody)
+	isAuthenticated, err := postRequest(ctx, httpClient, authenticationUrl, postBody)
 
 	return isAuthenticated, err
 
 }
 
-func postRequest(url *url.URL, postBody string) (bool, error) {
+func postRequest(ctx context.Context, client *http.Client, url *url.URL, postBody string) (bool, error) {
 	//fmt.Println("PostBody : " + postBody)
-	client := &http.Client{}
 	r, err := http.NewRequest("POST", url.String(), bytes.NewBufferString(postBody))
 	if err != nil {
 		return false, err
 	}
+	r = r.WithContext(ctx)
 
 	resp, err := client.Do(r)
 	if err != nil {
 		return false, err
 	}
+	defer resp.Body.Close()
 	//fmt.Println(resp.Status)
 	return strings.EqualFold(strconv.Itoa(resp.StatusCode), "201"), nil
 }
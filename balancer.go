@@ -3,70 +3,104 @@ package ortc
 import (
 	"fmt"
 	"io/ioutil"
-	"log"
 	"net/http"
+	"net/url"
 	"regexp"
 	"strings"
+	"time"
 )
 
-func getServerFromBalancer(balancerUrl, applicationKey string) string {
+const balancer_max_attempts = 3
+const balancer_retry_delay = 500 * time.Millisecond
+const balancer_max_retry_delay = 4 * time.Second
 
-	match, err := regexp.MatchString(`^(http(s)?).*$`, balancerUrl)
+var balancerResponse = regexp.MustCompile(`^var SOCKET_SERVER = "(.*)";$`)
 
-	if err != nil {
-		return "Error retrieving server from balancer"
+//serverUrl returns url, or a server of the balancer at url from balancer if isCluster is true.
+func serverUrl(httpClient *http.Client, balancer *balancerCache, url string, isCluster bool, applicationKey string) (string, error) {
+	if isCluster {
+		return balancer.server(httpClient, url, applicationKey)
 	}
+	return url, nil
+}
 
-	protocol := ""
-
-	if !match {
-		protocol = balancerUrl
+//getServerFromBalancer asks the cluster balancer for the url of a server, retrying failed requests with backoff.
+//The error is a *BalancerError.
+func getServerFromBalancer(httpClient *http.Client, balancerUrl, applicationKey string) (string, error) {
+	requestUrl, err := balancerRequestUrl(balancerUrl, applicationKey)
+	if err != nil {
+		return "", ortcBalancerException(balancerUrl, 0, err)
 	}
 
-	parsedUrl := fmt.Sprintf("%s%s", protocol, balancerUrl)
-
-	//fmt.Println("ParsedUrl " + parsedUrl)
+	backoff := &ExponentialBackoff{Initial: balancer_retry_delay, Max: balancer_max_retry_delay, Multiplier: 2}
+	start := time.Now()
+	attempt := 1
+	for {
+		server, err := unsecureRequest(httpClient, requestUrl)
+		if err == nil {
+			return server, nil
+		}
+		delay, ok := backoff.NextDelay(attempt, time.Since(start))
+		if !ok || attempt >= balancer_max_attempts {
+			return "", ortcBalancerException(balancerUrl, attempt, err)
+		}
+		time.Sleep(delay)
+		attempt++
+	}
+}
 
+//balancerRequestUrl validates the balancer url and adds the application key to its query.
+func balancerRequestUrl(balancerUrl, applicationKey string) (string, error) {
+	u, err := url.Parse(balancerUrl)
+	if err != nil {
+		return "", err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("invalid balancer url %q", balancerUrl)
+	}
 	if len(applicationKey) > 0 {
-		parsedUrl = parsedUrl + fmt.Sprintf("?appkey=%s", applicationKey)
+		query := u.Query()
+		query.Set("appkey", applicationKey)
+		u.RawQuery = query.Encode()
 	}
-
-	//fmt.Println("ParsedUrl after if: " + parsedUrl)
-	clusterServer := unsecureRequest(parsedUrl)
-
-	return clusterServer
+	return u.String(), nil
 }
 
-func unsecureRequest(url string) string {
+func unsecureRequest(httpClient *http.Client, url string) (string, error) {
 
-	resp, err := http.Get(url)
+	resp, err := httpClient.Get(url)
 
 	if err != nil {
-		log.Fatal(err)
+		return "", err
 	}
 
 	defer resp.Body.Close()
 	body, err := ioutil.ReadAll(resp.Body)
 
 	if err != nil {
-		return ""
+		return "", err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("balancer returned status %s", resp.Status)
 	}
 
-	line := string(body[:])
+	line := strings.TrimSpace(string(body[:]))
 	//fmt.Println("Line: " + line)
 
-	match, _ := regexp.MatchString(`^var SOCKET_SERVER = "(http.*)";$`, line)
+	match := balancerResponse.FindStringSubmatch(line)
 
-	if !match {
-		return "Server returned invalid server"
+	if match == nil {
+		return "", fmt.Errorf("balancer returned an invalid response %q", line)
 	}
 
-	balancerUrl := strings.Split(line, "=")[1]
-	balancerUrl = strings.Replace(balancerUrl, ";", "", -1)
-	balancerUrl = strings.Replace(balancerUrl, "\"", "", -1)
-	balancerUrl = strings.TrimSpace(balancerUrl)
-
-	//fmt.Println("Balancer: " + balancerUrl)
+	return validateServerUrl(match[1])
+}
 
-	return balancerUrl
+//validateServerUrl checks that the server returned by the balancer is a url the client can connect to.
+func validateServerUrl(server string) (string, error) {
+	if !ortcIsValidServerUrl(server) {
+		return "", fmt.Errorf("balancer returned an invalid server %q", server)
+	}
+	return server, nil
 }
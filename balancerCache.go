@@ -0,0 +1,106 @@
+package ortc
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+const balancer_cache_default_ttl = 60 * time.Second
+const balancer_max_known_servers = 5
+
+//defaultBalancerCache caches the balancer lookups of the package functions, such as GetPresence and SaveAuthentication.
+var defaultBalancerCache = newBalancerCache(balancer_cache_default_ttl)
+
+type balancerKey struct {
+	balancerUrl    string
+	applicationKey string
+}
+
+//balancerEntry holds the servers learned from the lookups of one balancer and application key.
+type balancerEntry struct {
+	//servers are the known-good servers, the last one returned by the balancer first.
+	servers    []string
+	resolvedAt time.Time
+	//failures counts the servers that failed since the last lookup.
+	failures int
+}
+
+//balancerCache remembers the servers returned by the cluster balancers, so reconnections and REST requests
+//do not wait for the balancer every time, and a failed server can be replaced by another known one.
+type balancerCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[balancerKey]*balancerEntry
+}
+
+func newBalancerCache(ttl time.Duration) *balancerCache {
+	return &balancerCache{ttl: ttl, entries: make(map[balancerKey]*balancerEntry)}
+}
+
+//servers returns the servers to try in order. The balancer is asked again once the lookup is older than
+//the cache ttl or every known server failed. If it cannot be reached the known servers are still returned.
+func (b *balancerCache) servers(httpClient *http.Client, balancerUrl, applicationKey string) ([]string, error) {
+	key := balancerKey{balancerUrl, applicationKey}
+
+	b.mu.Lock()
+	entry := b.entries[key]
+	if entry != nil && time.Since(entry.resolvedAt) < b.ttl && entry.failures < len(entry.servers) {
+		servers := append([]string(nil), entry.servers...)
+		b.mu.Unlock()
+		return servers, nil
+	}
+	b.mu.Unlock()
+
+	server, err := getServerFromBalancer(httpClient, balancerUrl, applicationKey)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	entry = b.entries[key]
+	if err != nil {
+		if entry != nil && len(entry.servers) > 0 {
+			return append([]string(nil), entry.servers...), nil
+		}
+		return nil, err
+	}
+	if entry == nil {
+		entry = &balancerEntry{}
+		b.entries[key] = entry
+	}
+	servers := []string{server}
+	for _, known := range entry.servers {
+		if known != server && len(servers) < balancer_max_known_servers {
+			servers = append(servers, known)
+		}
+	}
+	entry.servers = servers
+	entry.resolvedAt = time.Now()
+	entry.failures = 0
+	return append([]string(nil), servers...), nil
+}
+
+//server returns the first server to use for a REST request.
+func (b *balancerCache) server(httpClient *http.Client, balancerUrl, applicationKey string) (string, error) {
+	servers, err := b.servers(httpClient, balancerUrl, applicationKey)
+	if err != nil {
+		return "", err
+	}
+	return servers[0], nil
+}
+
+//failed moves server behind the other known servers after a connection to it failed.
+func (b *balancerCache) failed(balancerUrl, applicationKey, server string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	entry := b.entries[balancerKey{balancerUrl, applicationKey}]
+	if entry == nil {
+		return
+	}
+	for i, known := range entry.servers {
+		if known == server {
+			entry.servers = append(append(entry.servers[:i:i], entry.servers[i+1:]...), server)
+			entry.failures++
+			return
+		}
+	}
+}
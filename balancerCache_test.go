@@ -0,0 +1,151 @@
+package ortc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+//fakeBalancer is a cluster balancer for the tests, answering with the server set by answer.
+type fakeBalancer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	server   string
+	requests int
+}
+
+func newFakeBalancer(t *testing.T, server string) *fakeBalancer {
+	fb := &fakeBalancer{server: server}
+	fb.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fb.mu.Lock()
+		defer fb.mu.Unlock()
+		fb.requests++
+		if r.URL.Query().Get("appkey") != "appKey" {
+			http.Error(w, "missing appkey", http.StatusBadRequest)
+			return
+		}
+		if fb.server == "" {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintf(w, "var SOCKET_SERVER = \"%s\";", fb.server)
+	}))
+	t.Cleanup(fb.Close)
+	return fb
+}
+
+//answer sets the server returned by the balancer, or makes it fail if server is empty.
+func (fb *fakeBalancer) answer(server string) {
+	fb.mu.Lock()
+	defer fb.mu.Unlock()
+	fb.server = server
+}
+
+func (fb *fakeBalancer) requestCount() int {
+	fb.mu.Lock()
+	defer fb.mu.Unlock()
+	return fb.requests
+}
+
+//deadServer returns the url of a server that refuses connections.
+func deadServer() string {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+	return server.URL
+}
+
+func TestBalancerCacheSkipsBalancer(t *testing.T) {
+	fb := newFakeBalancer(t, "http://server1")
+	b := newBalancerCache(time.Minute)
+
+	for i := 0; i < 3; i++ {
+		server, err := b.server(http.DefaultClient, fb.URL, "appKey")
+		if err != nil || server != "http://server1" {
+			t.Fatalf("server = %q, %v", server, err)
+		}
+	}
+	if n := fb.requestCount(); n != 1 {
+		t.Fatalf("balancer asked %d times, want 1", n)
+	}
+
+	//Once every known server failed the balancer is asked again.
+	fb.answer("http://server2")
+	b.failed(fb.URL, "appKey", "http://server1")
+	servers, err := b.servers(http.DefaultClient, fb.URL, "appKey")
+	if err != nil || strings.Join(servers, ",") != "http://server2,http://server1" {
+		t.Fatalf("servers = %v, %v", servers, err)
+	}
+	if n := fb.requestCount(); n != 2 {
+		t.Fatalf("balancer asked %d times, want 2", n)
+	}
+}
+
+func TestBalancerCacheUnreachableBalancer(t *testing.T) {
+	fb := newFakeBalancer(t, "http://server1")
+	b := newBalancerCache(0)
+	if _, err := b.servers(http.DefaultClient, fb.URL, "appKey"); err != nil {
+		t.Fatalf("servers: %v", err)
+	}
+
+	//The known servers are used while the balancer fails.
+	fb.answer("")
+	servers, err := b.servers(http.DefaultClient, fb.URL, "appKey")
+	if err != nil || strings.Join(servers, ",") != "http://server1" {
+		t.Fatalf("servers with the balancer failing = %v, %v", servers, err)
+	}
+	if n := fb.requestCount(); n != 1+balancer_max_attempts {
+		t.Fatalf("balancer asked %d times, want %d", n, 1+balancer_max_attempts)
+	}
+
+	//Without known servers the balancer error is returned.
+	fb.Close()
+	var balancerError *BalancerError
+	if _, err := newBalancerCache(0).servers(http.DefaultClient, fb.URL, "appKey"); !errors.As(err, &balancerError) ||
+		balancerError.Attempts != balancer_max_attempts || !errors.Is(err, ErrBalancer) {
+		t.Fatalf("servers with an unreachable balancer = %v, want a *BalancerError", err)
+	}
+}
+
+func TestClusterDialFailover(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	fs := newFakeServer(t)
+	fb := newFakeBalancer(t, fs.URL)
+	c := NewClient(WithEventHandler(HandlerFuncs{}), WithBalancerCacheTTL(0))
+	if err := c.ConnectContext(ctx, "appKey", "token", "", fb.URL, true, false); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	c.Disconnect()
+
+	//The balancer now returns a server that cannot be reached, the known one is tried next.
+	dead := deadServer()
+	fb.answer(dead)
+	if err := c.ConnectContext(ctx, "appKey", "token", "", fb.URL, true, false); err != nil {
+		t.Fatalf("connect with the balancer returning a dead server: %v", err)
+	}
+	defer c.Disconnect()
+	if url := c.GetUrl(); url != fb.URL {
+		t.Fatalf("GetUrl = %s, want the cluster url", url)
+	}
+	c.mu.Lock()
+	server := c.serverUrl
+	c.mu.Unlock()
+	if server != fs.URL {
+		t.Fatalf("connected to %s, want %s", server, fs.URL)
+	}
+
+	//The dead server is moved behind the one that works.
+	c.balancer.mu.Lock()
+	servers := strings.Join(c.balancer.entries[balancerKey{fb.URL, "appKey"}].servers, ",")
+	c.balancer.mu.Unlock()
+	if servers != fs.URL+","+dead {
+		t.Fatalf("known servers = %s", servers)
+	}
+}
@@ -1,22 +1,49 @@
 package ortc
 
+import "sync"
+
+//Message is a message received on a subscribed channel.
+type Message = onMessageChannel
+
 type onMessageChannel struct {
 	Sender  *OrtcClient
 	Channel string
 	Message string
+	//Filtered is set on the messages of a subscription made with SubscribeWithFilter when the server applied the filter.
+	Filtered bool
+	//SeqId is the sequence id of the message, set when the server buffers the messages of the channel.
+	SeqId string
 }
 
 type channelSubscription struct {
 	isSubscribing        bool
 	isSubscribed         bool
 	subscribeOnReconnect bool
+	filter               string
 	onMessage            chan onMessageChannel
+	//registrationId and platform identify the device the messages are pushed to. registrationId is guarded by the client mutex.
+	registrationId string
+	platform       Platform
+	//updatingRegistration is set while the server has not confirmed the registration id set by UpdateRegistrationId.
+	//previousRegistrationId is the id restored if the server rejects it.
+	updatingRegistration   bool
+	previousRegistrationId string
+	//sentRegistrationId is the id of a subscribe command still waiting for the server when UpdateRegistrationId
+	//replaced it. The new id is sent once the subscription is confirmed.
+	sentRegistrationId string
+
+	//mu serializes deliveries with close, done unblocks a pending delivery when closing.
+	mu        sync.Mutex
+	closed    bool
+	done      chan struct{}
+	closeOnce sync.Once
 }
 
-func newChannelSubscription(subscribeOnReconnected bool) *channelSubscription {
+func newChannelSubscription(subscribeOnReconnected bool, bufferSize int) *channelSubscription {
 	chanSub := new(channelSubscription)
 	chanSub.subscribeOnReconnect = subscribeOnReconnected
-	chanSub.onMessage = make(chan onMessageChannel)
+	chanSub.onMessage = make(chan onMessageChannel, bufferSize)
+	chanSub.done = make(chan struct{})
 	chanSub.isSubscribed = false
 	chanSub.isSubscribing = false
 	return chanSub
@@ -33,3 +60,48 @@ func (c *channelSubscription) subscribed() bool {
 func (c *channelSubscription) subscribeOnReconnected() bool {
 	return c.subscribeOnReconnect
 }
+
+//deliver writes a message to the subscription channel following policy.
+//Messages delivered after close are discarded.
+func (c *channelSubscription) deliver(client *OrtcClient, policy OverflowPolicy, msg onMessageChannel) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return
+	}
+
+	deliver(client, policy, StreamSubscription, func(block bool) bool {
+		if block {
+			select {
+			case c.onMessage <- msg:
+				return true
+			case <-c.done:
+				return false
+			}
+		}
+		select {
+		case c.onMessage <- msg:
+			return true
+		default:
+			return false
+		}
+	}, func() bool {
+		select {
+		case <-c.onMessage:
+			return true
+		default:
+			return false
+		}
+	})
+}
+
+//close closes the subscription channel. It is safe to call it more than once.
+func (c *channelSubscription) close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+		c.mu.Lock()
+		c.closed = true
+		close(c.onMessage)
+		c.mu.Unlock()
+	})
+}
@@ -0,0 +1,119 @@
+package ortc
+
+import (
+	"context"
+	"errors"
+	"github.com/gorilla/websocket"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var errConnectionClosed = errors.New("Connection closed")
+
+//connection wraps a single websocket connection of an OrtcClient.
+//All frames are written by a dedicated writer goroutine, since the websocket
+//connection supports only one concurrent writer.
+type connection struct {
+	ws *websocket.Conn
+	//outbound is the bounded write queue. The frames of a message are queued as a single entry,
+	//so the parts of a multipart message are never interleaved with other frames.
+	outbound      chan [][]byte
+	writeTimeout  time.Duration
+	done          chan struct{}
+	closeOnce     sync.Once
+	lastHeartBeat int64
+}
+
+func newConnection(ws *websocket.Conn, queueSize int, writeTimeout time.Duration, onWriteError func(*connection, error)) *connection {
+	conn := new(connection)
+	conn.ws = ws
+	conn.outbound = make(chan [][]byte, queueSize)
+	conn.writeTimeout = writeTimeout
+	conn.done = make(chan struct{})
+	conn.touch()
+	go conn.writeLoop(onWriteError)
+	return conn
+}
+
+//writeLoop writes the queued frames until the connection is closed or a write fails,
+//since the websocket connection is unusable after a failed write.
+func (conn *connection) writeLoop(onWriteError func(*connection, error)) {
+	for {
+		select {
+		case frames := <-conn.outbound:
+			for _, frame := range frames {
+				if conn.writeTimeout > 0 {
+					conn.ws.SetWriteDeadline(time.Now().Add(conn.writeTimeout))
+				}
+				err := conn.ws.WriteMessage(websocket.TextMessage, frame)
+				if err != nil {
+					onWriteError(conn, err)
+					return
+				}
+			}
+		case <-conn.done:
+			return
+		}
+	}
+}
+
+//write queues frames for the writer goroutine, waiting for room in the queue.
+//It returns errConnectionClosed if the connection was closed before the frames could be queued,
+//or the context error if ctx is done first.
+func (conn *connection) write(ctx context.Context, frames ...[]byte) error {
+	if conn.isClosed() {
+		//Frames queued now would never be written.
+		return errConnectionClosed
+	}
+	select {
+	case conn.outbound <- frames:
+		return nil
+	case <-conn.done:
+		return errConnectionClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+//tryWrite queues frames for the writer goroutine, or returns ErrQueueFull if the queue is full.
+func (conn *connection) tryWrite(frames ...[]byte) error {
+	if conn.isClosed() {
+		return errConnectionClosed
+	}
+	select {
+	case conn.outbound <- frames:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+//close closes the websocket connection.
+//It returns true only for the call that actually closed it, so the disconnection is handled once.
+func (conn *connection) close() bool {
+	closed := false
+	conn.closeOnce.Do(func() {
+		close(conn.done)
+		conn.ws.Close()
+		closed = true
+	})
+	return closed
+}
+
+func (conn *connection) isClosed() bool {
+	select {
+	case <-conn.done:
+		return true
+	default:
+		return false
+	}
+}
+
+func (conn *connection) touch() {
+	atomic.StoreInt64(&conn.lastHeartBeat, time.Now().UnixNano())
+}
+
+func (conn *connection) sinceLastHeartBeat() time.Duration {
+	return time.Since(time.Unix(0, atomic.LoadInt64(&conn.lastHeartBeat)))
+}
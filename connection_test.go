@@ -0,0 +1,63 @@
+package ortc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteQueueFullAndWriteTimeout(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	fs := newFakeServer(t)
+	//The server stops reading at the first sent message, so the writes of the client block.
+	release := make(chan struct{})
+	t.Cleanup(func() { close(release) })
+	fs.handle(func(fc *fakeConn, frame string) bool {
+		if strings.HasPrefix(frame, "send;") {
+			<-release
+			return true
+		}
+		return false
+	})
+	exceptions := make(chan error, 4)
+	c := connectClient(t, ctx, fs, WithWriteQueueSize(1), WithWriteTimeout(500*time.Millisecond),
+		WithMaxMessageSize(1<<20), WithReconnectPolicy(fixedDelay(time.Minute)),
+		WithEventHandler(HandlerFuncs{
+			Exception: func(c *OrtcClient, err error) {
+				select {
+				case exceptions <- err:
+				default:
+				}
+			},
+		}))
+
+	//The parts of the large message fill the socket buffers, and the next message fills the write queue.
+	if err := c.SendContext(ctx, "channel", strings.Repeat("x", 32<<20)); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	if err := c.SendContext(ctx, "channel", "queued"); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	if err := c.TrySend("channel", "full"); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("TrySend = %v, want ErrQueueFull", err)
+	}
+
+	//The blocked write fails at the write deadline and the connection is treated as lost.
+	var netError net.Error
+	select {
+	case err := <-exceptions:
+		if !errors.As(err, &netError) || !netError.Timeout() {
+			t.Fatalf("exception = %v, want a write timeout", err)
+		}
+	case <-ctx.Done():
+		t.Fatal("the write did not time out")
+	}
+	waitFor(t, "the connection to be lost", func() bool { return c.State() == StateReconnecting })
+	if err := c.TrySend("channel", "lost"); !errors.Is(err, ErrNotConnected) {
+		t.Fatalf("TrySend after the connection was lost = %v, want ErrNotConnected", err)
+	}
+}
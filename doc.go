@@ -15,6 +15,22 @@
 //
 // client, onConnected, onDisconnected, onException, onMessage, onReconnected, onReconnecting, onSubscribed, onUnsubscribed := ortc.NewOrtcClient()
 //
+// - Create a new instance of ortc client with custom settings:
+//
+// client := ortc.NewClient(ortc.WithReconnectDelay(2*time.Second), ortc.WithEventBufferSize(100), ortc.WithLogger(log.New(os.Stderr, "", log.LstdFlags)))
+// events := client.Events()
+//
+// - Create a new instance of ortc client handling only some of the events:
+//
+// client := ortc.NewClient(ortc.WithEventHandler(ortc.HandlerFuncs{
+//	Connected: func(c *ortc.OrtcClient) {
+//		c.Subscribe("my_channel", true)
+//	},
+//	Message: func(c *ortc.OrtcClient, channel, message string) {
+//		fmt.Println("RECEIVED MESSAGE: " + message + " ON CHANNEL: " + channel)
+//	},
+// }))
+//
 // - Using the channels received on the ortc client:
 //
 // 		//Create a go routine that start listening from the Ortc events channels.
@@ -26,7 +42,7 @@
 //				case sender := <-onDisconnected:
 //					fmt.Println("CLIENT DISCONNECTED FROM: " + sender.GetUrl())
 //				case sender := <-onException:
-//					fmt.Println("CLIENT EXCEPTION: " + sender.Err)
+//					fmt.Println("CLIENT EXCEPTION: " + sender.Err.Error())
 //				case msgObj := <-onMessage:
 //					fmt.Println("RECEIVED MESSAGE: " + msgObj.Message + " ON CHANNEL: " + msgObj.Channel)
 //				case sender := <-onReconnected:
@@ -45,6 +61,19 @@
 //
 // client.Connect("YOUR_APPLICATION_KEY", "myToken", "GoApp", "http://ortc-developers.realtime.co/server/2.1", true, false)
 //
+// - Connect and subscribe waiting for the server confirmation:
+//
+// ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+// defer cancel()
+//
+// if err := client.ConnectContext(ctx, "YOUR_APPLICATION_KEY", "myToken", "GoApp", "http://ortc-developers.realtime.co/server/2.1", true, false); err != nil {
+//	fmt.Println(err)
+// }
+//
+// if _, err := client.SubscribeContext(ctx, "my_channel", true); errors.Is(err, ortc.ErrNoPermission) {
+//	fmt.Println("Not allowed to subscribe my_channel")
+// }
+//
 // - Disconnect from ortc server:
 //
 // client.Disconnect()
@@ -117,18 +146,70 @@
 //	fmt.Println("Unable to authenticate")
 // }
 //
+// - Save the channels permissions of an authentication token, knowing why it failed:
+//
+// if ok, err := ortc.SaveAuthenticationContext(ctx, "http://ortc-developers.realtime.co/server/2.1", true, "myToken", false, "YOUR_APPLICATION_KEY", 14000, "YOUR_PRIVATE_KEY", permissions); err != nil {
+//	fmt.Println(err)
+// } else if !ok {
+//	fmt.Println("Permissions rejected")
+// }
+//
 // - Send message to a channel:
 //
 // client.Send("my_channel", "Hello World!")
 //
+// - Publish a message stored for 60 seconds and wait for the server acknowledgement:
+//
+// seqId, err := client.Publish(ctx, "my_channel", "Hello World!", 60*time.Second)
+//
+// - Send a message without connecting, authenticated by the private key:
+//
+// err := ortc.SendREST(ctx, "http://ortc-developers.realtime.co/server/2.1", true, "YOUR_APPLICATION_KEY", "YOUR_PRIVATE_KEY", "my_channel", "Hello World!")
+//
 // - Subscribe to a channel:
 //
 // client.Subscribe("my_channel", true)
 //
+// - Read the messages of a single channel:
+//
+// for msg := range client.Subscribe("my_channel", true) {
+//	fmt.Println(msg.Message)
+// }
+//
+// - Subscribe to a channel receiving only the messages matching a filter:
+//
+// client.SubscribeWithFilter("my_channel", true, "message.priority > 2")
+//
+// - Handle the messages with their Filtered flag and sequence id:
+//
+// client := ortc.NewClient(ortc.WithEventHandler(ortc.HandlerFuncs{
+//	MessageReceived: func(c *ortc.OrtcClient, msg ortc.Message) {
+//		fmt.Println("RECEIVED MESSAGE: " + msg.Message + " FILTERED: " + strconv.FormatBool(msg.Filtered) + " SEQ: " + msg.SeqId)
+//	},
+// }))
+//
+// - Resume a subscription from a stored checkpoint, replaying the messages missed meanwhile:
+//
+// messages, err := client.SubscribeWithOptions(ctx, "my_channel", ortc.SubscribeOptions{SubscribeOnReconnect: true, SeqId: checkpoint})
+// ...
+// checkpoint = client.LastSeqId("my_channel")
+//
+// - Subscribe to a channel forwarding its messages to a mobile device as push notifications:
+//
+// client.SubscribeWithNotifications("my_channel", true, "DEVICE_REGISTRATION_ID", ortc.PlatformGCM)
+//
 // - Unsubscribe from a channel:
 //
 // client.Unsubscribe("my_channel")
 //
+// - Receive the announcements of a sub-channel, set before connecting:
+//
+// client.SetAnnouncementSubChannel("my_subchannel")
+// announcements, err := client.SubscribeAnnouncements(ctx, true)
+// for a := range announcements {
+//	fmt.Println(a.Type, a.Metadata, a.IP, a.Channel)
+// }
+//
 // More documentation about the Realtime Messaging service (ORTC) can be found at: 
 // http://messaging-public.realtime.co/documentation/starting-guide/overview.html
 package ortc
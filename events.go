@@ -0,0 +1,351 @@
+package ortc
+
+import "sync/atomic"
+
+//EventHandler receives the events of an OrtcClient. It is an alternative to the event channels,
+//set with the WithEventHandler option.
+//
+//The methods are called synchronously from the client goroutines, so they must not block.
+//Client methods may be called from a handler, except the Context variants that wait for a
+//server confirmation, since that confirmation is read by the goroutine running the handler.
+type EventHandler interface {
+	OnConnected(c *OrtcClient)
+	OnDisconnected(c *OrtcClient)
+	OnException(c *OrtcClient, err error)
+	OnMessage(c *OrtcClient, channel, message string)
+	OnReconnecting(c *OrtcClient, attempt ReconnectAttempt)
+	OnReconnected(c *OrtcClient)
+	OnSubscribed(c *OrtcClient, channel string)
+	OnUnsubscribed(c *OrtcClient, channel string)
+}
+
+//MessageHandler is implemented by an EventHandler that receives the messages with their Filtered flag
+//and SeqId. The client calls OnMessageReceived instead of OnMessage on such handlers.
+type MessageHandler interface {
+	OnMessageReceived(c *OrtcClient, msg Message)
+}
+
+//HandlerFuncs is an EventHandler calling the function set for each event.
+//Events whose function is nil are ignored.
+type HandlerFuncs struct {
+	Connected    func(c *OrtcClient)
+	Disconnected func(c *OrtcClient)
+	Exception    func(c *OrtcClient, err error)
+	Message      func(c *OrtcClient, channel, message string)
+	Reconnecting func(c *OrtcClient, attempt ReconnectAttempt)
+	Reconnected  func(c *OrtcClient)
+	Subscribed   func(c *OrtcClient, channel string)
+	Unsubscribed func(c *OrtcClient, channel string)
+
+	//MessageReceived receives the messages with their Filtered flag and SeqId. When set, Message is not called.
+	MessageReceived func(c *OrtcClient, msg Message)
+}
+
+func (h HandlerFuncs) OnConnected(c *OrtcClient) {
+	if h.Connected != nil {
+		h.Connected(c)
+	}
+}
+
+func (h HandlerFuncs) OnDisconnected(c *OrtcClient) {
+	if h.Disconnected != nil {
+		h.Disconnected(c)
+	}
+}
+
+func (h HandlerFuncs) OnException(c *OrtcClient, err error) {
+	if h.Exception != nil {
+		h.Exception(c, err)
+	}
+}
+
+func (h HandlerFuncs) OnMessage(c *OrtcClient, channel, message string) {
+	if h.Message != nil {
+		h.Message(c, channel, message)
+	}
+}
+
+func (h HandlerFuncs) OnMessageReceived(c *OrtcClient, msg Message) {
+	if h.MessageReceived != nil {
+		h.MessageReceived(c, msg)
+	} else {
+		h.OnMessage(c, msg.Channel, msg.Message)
+	}
+}
+
+func (h HandlerFuncs) OnReconnecting(c *OrtcClient, attempt ReconnectAttempt) {
+	if h.Reconnecting != nil {
+		h.Reconnecting(c, attempt)
+	}
+}
+
+func (h HandlerFuncs) OnReconnected(c *OrtcClient) {
+	if h.Reconnected != nil {
+		h.Reconnected(c)
+	}
+}
+
+func (h HandlerFuncs) OnSubscribed(c *OrtcClient, channel string) {
+	if h.Subscribed != nil {
+		h.Subscribed(c, channel)
+	}
+}
+
+func (h HandlerFuncs) OnUnsubscribed(c *OrtcClient, channel string) {
+	if h.Unsubscribed != nil {
+		h.Unsubscribed(c, channel)
+	}
+}
+
+//Events holds the ortc callback event channels of a client.
+type Events struct {
+	OnConnected    <-chan *OrtcClient
+	OnDisconnected <-chan *OrtcClient
+	OnException    <-chan exceptionOrtc
+	OnMessage      <-chan onMessageChannel
+	OnReconnected  <-chan *OrtcClient
+	OnReconnecting <-chan *OrtcClient
+	OnSubscribed   <-chan subsOrtc
+	OnUnsubscribed <-chan subsOrtc
+	//OnReconnectAttempt receives the attempt number and delay of every reconnection scheduled,
+	//along with OnReconnecting. It never blocks the client: when its buffer is full the oldest attempt is dropped.
+	OnReconnectAttempt <-chan ReconnectAttempt
+}
+
+//EventStream identifies one of the event channels of a client.
+type EventStream int
+
+const (
+	StreamConnected EventStream = iota
+	StreamDisconnected
+	StreamException
+	StreamMessage
+	StreamReconnected
+	StreamReconnecting
+	StreamSubscribed
+	StreamUnsubscribed
+	//StreamSubscription counts the messages dropped from the channels returned by Subscribe.
+	StreamSubscription
+	//StreamState counts the changes dropped from the StateChanges channel.
+	StreamState
+	//StreamOfflineQueue counts the messages dropped from the offline queue.
+	StreamOfflineQueue
+	//StreamReconnectAttempt counts the attempts dropped from the OnReconnectAttempt channel.
+	StreamReconnectAttempt
+	numStreams
+)
+
+//OverflowPolicy decides what happens to an event when its channel buffer is full.
+type OverflowPolicy int
+
+const (
+	//OverflowBlock waits until the consumer reads from the channel. This is the default.
+	OverflowBlock OverflowPolicy = iota
+	//OverflowDropOldest discards the oldest buffered event to make room for the new one.
+	OverflowDropOldest
+	//OverflowDropNewest discards the new event.
+	OverflowDropNewest
+	//OverflowDisconnect discards the new event and disconnects the client.
+	OverflowDisconnect
+)
+
+//channelHandler is the EventHandler delivering the events on the client event channels.
+type channelHandler struct {
+	policy OverflowPolicy
+
+	onConnectedChannel    chan *OrtcClient
+	onDisconnectedChannel chan *OrtcClient
+	onExceptionChannel    chan exceptionOrtc
+	onMessageChannel      chan onMessageChannel
+	onReconnectedChannel  chan *OrtcClient
+	onReconnectingChannel chan *OrtcClient
+	onSubscribedChannel   chan subsOrtc
+	onUnsubscribedChannel chan subsOrtc
+
+	onReconnectAttemptChannel chan ReconnectAttempt
+}
+
+func newChannelHandler(o *options) *channelHandler {
+	h := new(channelHandler)
+	h.policy = o.overflowPolicy
+	h.onConnectedChannel = make(chan *OrtcClient, o.streamBufferSize(StreamConnected))
+	h.onDisconnectedChannel = make(chan *OrtcClient, o.streamBufferSize(StreamDisconnected))
+	h.onExceptionChannel = make(chan exceptionOrtc, o.streamBufferSize(StreamException))
+	h.onMessageChannel = make(chan onMessageChannel, o.streamBufferSize(StreamMessage))
+	h.onReconnectedChannel = make(chan *OrtcClient, o.streamBufferSize(StreamReconnected))
+	h.onReconnectingChannel = make(chan *OrtcClient, o.streamBufferSize(StreamReconnecting))
+	h.onSubscribedChannel = make(chan subsOrtc, o.streamBufferSize(StreamSubscribed))
+	h.onUnsubscribedChannel = make(chan subsOrtc, o.streamBufferSize(StreamUnsubscribed))
+	h.onReconnectAttemptChannel = make(chan ReconnectAttempt, o.streamBufferSize(StreamReconnectAttempt))
+	return h
+}
+
+func (h *channelHandler) events() Events {
+	return Events{h.onConnectedChannel, h.onDisconnectedChannel, h.onExceptionChannel, h.onMessageChannel,
+		h.onReconnectedChannel, h.onReconnectingChannel, h.onSubscribedChannel, h.onUnsubscribedChannel,
+		h.onReconnectAttemptChannel}
+}
+
+//deliver applies the overflow policy to an event of stream.
+//send writes the event to its channel, without blocking unless block is true, and reports whether it was written.
+//dropOldest discards the oldest buffered event of the channel, if any.
+func deliver(c *OrtcClient, policy OverflowPolicy, stream EventStream, send func(block bool) bool, dropOldest func() bool) {
+	switch policy {
+	case OverflowDropOldest:
+		for !send(false) {
+			atomic.AddUint64(&c.dropped[stream], 1)
+			if !dropOldest() {
+				//Unbuffered channel without a ready consumer: the new event is the one dropped.
+				return
+			}
+		}
+	case OverflowDropNewest:
+		if !send(false) {
+			atomic.AddUint64(&c.dropped[stream], 1)
+		}
+	case OverflowDisconnect:
+		if !send(false) {
+			atomic.AddUint64(&c.dropped[stream], 1)
+			go c.overflowDisconnect(stream)
+		}
+	default:
+		send(true)
+	}
+}
+
+func (h *channelHandler) OnConnected(c *OrtcClient) {
+	h.deliverClient(c, StreamConnected, h.onConnectedChannel)
+}
+
+func (h *channelHandler) OnDisconnected(c *OrtcClient) {
+	h.deliverClient(c, StreamDisconnected, h.onDisconnectedChannel)
+}
+
+func (h *channelHandler) OnException(c *OrtcClient, err error) {
+	ch := h.onExceptionChannel
+	ev := exceptionOrtc{c, err}
+	deliver(c, h.policy, StreamException, func(block bool) bool {
+		if block {
+			ch <- ev
+			return true
+		}
+		select {
+		case ch <- ev:
+			return true
+		default:
+			return false
+		}
+	}, func() bool {
+		select {
+		case <-ch:
+			return true
+		default:
+			return false
+		}
+	})
+}
+
+func (h *channelHandler) OnMessage(c *OrtcClient, channel, message string) {
+	h.OnMessageReceived(c, Message{c, channel, message, false, ""})
+}
+
+func (h *channelHandler) OnMessageReceived(c *OrtcClient, ev Message) {
+	ch := h.onMessageChannel
+	deliver(c, h.policy, StreamMessage, func(block bool) bool {
+		if block {
+			ch <- ev
+			return true
+		}
+		select {
+		case ch <- ev:
+			return true
+		default:
+			return false
+		}
+	}, func() bool {
+		select {
+		case <-ch:
+			return true
+		default:
+			return false
+		}
+	})
+}
+
+//OnReconnecting delivers the client on the OnReconnecting channel and the attempt on the OnReconnectAttempt channel.
+func (h *channelHandler) OnReconnecting(c *OrtcClient, attempt ReconnectAttempt) {
+	ch := h.onReconnectAttemptChannel
+	deliver(c, OverflowDropOldest, StreamReconnectAttempt, func(block bool) bool {
+		select {
+		case ch <- attempt:
+			return true
+		default:
+			return false
+		}
+	}, func() bool {
+		select {
+		case <-ch:
+			return true
+		default:
+			return false
+		}
+	})
+	h.deliverClient(c, StreamReconnecting, h.onReconnectingChannel)
+}
+
+func (h *channelHandler) OnReconnected(c *OrtcClient) {
+	h.deliverClient(c, StreamReconnected, h.onReconnectedChannel)
+}
+
+func (h *channelHandler) OnSubscribed(c *OrtcClient, channel string) {
+	h.deliverSubs(c, StreamSubscribed, h.onSubscribedChannel, channel)
+}
+
+func (h *channelHandler) OnUnsubscribed(c *OrtcClient, channel string) {
+	h.deliverSubs(c, StreamUnsubscribed, h.onUnsubscribedChannel, channel)
+}
+
+func (h *channelHandler) deliverClient(c *OrtcClient, stream EventStream, ch chan *OrtcClient) {
+	deliver(c, h.policy, stream, func(block bool) bool {
+		if block {
+			ch <- c
+			return true
+		}
+		select {
+		case ch <- c:
+			return true
+		default:
+			return false
+		}
+	}, func() bool {
+		select {
+		case <-ch:
+			return true
+		default:
+			return false
+		}
+	})
+}
+
+func (h *channelHandler) deliverSubs(c *OrtcClient, stream EventStream, ch chan subsOrtc, channel string) {
+	ev := subsOrtc{c, channel}
+	deliver(c, h.policy, stream, func(block bool) bool {
+		if block {
+			ch <- ev
+			return true
+		}
+		select {
+		case ch <- ev:
+			return true
+		default:
+			return false
+		}
+	}, func() bool {
+		select {
+		case <-ch:
+			return true
+		default:
+			return false
+		}
+	})
+}
@@ -0,0 +1,124 @@
+package ortc
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+//filteringServer answers filtered subscriptions with a filtered message carrying a sequence id.
+func filteringServer(t *testing.T) *fakeServer {
+	fs := newFakeServer(t)
+	fs.handle(func(fc *fakeConn, frame string) bool {
+		if !strings.HasPrefix(frame, "subscribefilter;") {
+			return false
+		}
+		channel := strings.Split(frame, ";")[3]
+		fc.writeOp("ortc-subscribed", fmt.Sprintf(`"ch":"%s"`, channel))
+		fc.write(fmt.Sprintf(`a["{\"ch\":\"%s\",\"f\":true,\"s\":\"seq-1\",\"m\":\"abcdefgh_1-1_hello\"}"]`, channel))
+		return true
+	})
+	return fs
+}
+
+func checkFiltered(t *testing.T, stream string, msg Message) {
+	t.Helper()
+	if msg.Channel != "channel" || msg.Message != "hello" || !msg.Filtered || msg.SeqId != "seq-1" {
+		t.Errorf("%s delivered %+v, want a filtered message with sequence id seq-1", stream, msg)
+	}
+}
+
+func TestGlobalMessageStreamFields(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	c := NewClient(WithEventBufferSize(16))
+	if err := c.ConnectContext(ctx, "appKey", "token", "", filteringServer(t).URL, false, false); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer c.Disconnect()
+
+	onMessage, err := c.SubscribeWithFilterContext(ctx, "channel", false, "message.a = 1")
+	if err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+	for stream, ch := range map[string]<-chan onMessageChannel{"subscription": onMessage, "OnMessage": c.Events().OnMessage} {
+		select {
+		case msg := <-ch:
+			checkFiltered(t, stream, msg)
+		case <-ctx.Done():
+			t.Fatalf("no message on the %s channel", stream)
+		}
+	}
+}
+
+func TestHandlerFuncsMessageReceived(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	received := make(chan Message, 1)
+	c := connectClient(t, ctx, filteringServer(t), WithEventHandler(HandlerFuncs{
+		Message: func(c *OrtcClient, channel, message string) {
+			t.Errorf("Message called along with MessageReceived")
+		},
+		MessageReceived: func(c *OrtcClient, msg Message) {
+			received <- msg
+		},
+	}))
+
+	if _, err := c.SubscribeWithFilterContext(ctx, "channel", false, "message.a = 1"); err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+	select {
+	case msg := <-received:
+		checkFiltered(t, "MessageReceived", msg)
+	case <-ctx.Done():
+		t.Fatal("MessageReceived not called")
+	}
+}
+
+func TestOverflowPolicies(t *testing.T) {
+	tests := []struct {
+		policy   OverflowPolicy
+		channels []string
+		want     []string
+	}{
+		{OverflowDropNewest, []string{"a", "b", "c"}, []string{"a"}},
+		{OverflowDropOldest, []string{"a", "b", "c"}, []string{"c"}},
+		{OverflowDisconnect, []string{"a", "b"}, []string{"a"}},
+	}
+	for _, tt := range tests {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		fs := newFakeServer(t)
+		c := NewClient(WithOverflowPolicy(tt.policy), WithEventBufferSize(16), WithStreamBufferSize(StreamSubscribed, 1))
+		if err := c.ConnectContext(ctx, "appKey", "token", "", fs.URL, false, false); err != nil {
+			t.Fatalf("policy %v: connect: %v", tt.policy, err)
+		}
+		//Nobody reads the OnSubscribed channel while subscribing.
+		for _, channel := range tt.channels {
+			if _, err := c.SubscribeContext(ctx, channel, false); err != nil {
+				t.Fatalf("policy %v: subscribe %s: %v", tt.policy, channel, err)
+			}
+		}
+
+		//The events are delivered after the subscriptions are confirmed.
+		waitFor(t, "the events to be dropped", func() bool {
+			return c.DroppedEvents(StreamSubscribed) == uint64(len(tt.channels)-len(tt.want))
+		})
+		if tt.policy == OverflowDisconnect {
+			waitFor(t, "the client to disconnect", func() bool { return c.State() == StateClosed })
+		} else {
+			c.Disconnect()
+		}
+		events := c.Events()
+		for _, want := range tt.want {
+			if subscribed := <-events.OnSubscribed; subscribed.Channel != want {
+				t.Errorf("policy %v: OnSubscribed delivered %s, want %s", tt.policy, subscribed.Channel, want)
+			}
+		}
+		if len(events.OnSubscribed) != 0 {
+			t.Errorf("policy %v: %d more events buffered", tt.policy, len(events.OnSubscribed))
+		}
+	}
+}
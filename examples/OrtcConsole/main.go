@@ -306,7 +306,7 @@ func initClient() *ortc.OrtcClient {
 				fmt.Println("CLIENT DISCONNECTED FROM: " + sender.GetUrl())
 				channelConsole <- true
 			case sender := <-onException:
-				fmt.Println("CLIENT EXCEPTION: " + sender.Err)
+				fmt.Println("CLIENT EXCEPTION: " + sender.Err.Error())
 				channelConsole <- true
 			case msgObj := <-onMessage:
 				fmt.Println("RECEIVED MESSAGE: " + msgObj.Message + " ON CHANNEL: " + msgObj.Channel)
@@ -368,7 +368,7 @@ func initClient() *ortc.OrtcClient {
 				fmt.Println("CLIENT DISCONNECTED FROM: " + sender.GetUrl())
 
 			case sender := <-onException:
-				fmt.Println("CLIENT EXCEPTION: " + sender.Err)
+				fmt.Println("CLIENT EXCEPTION: " + sender.Err.Error())
 
 			case msgObj := <-onMessage:
 				fmt.Println("RECEIVED MESSAGE: " + msgObj.Message + " ON CHANNEL: " + msgObj.Channel)
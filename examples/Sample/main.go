@@ -31,7 +31,7 @@ func main() {
 			case sender := <-onDisconnected:
 				fmt.Println("CLIENT DISCONNECTED FROM: " + sender.GetUrl())
 			case sender := <-onException:
-				fmt.Println("CLIENT EXCEPTION: " + sender.Err)
+				fmt.Println("CLIENT EXCEPTION: " + sender.Err.Error())
 			case msgObj := <-onMessage:
 				fmt.Println("RECEIVED MESSAGE: " + msgObj.Message + " ON CHANNEL: " + msgObj.Channel)
 			case sender := <-onReconnected:
@@ -1,60 +1,280 @@
 package ortc
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+)
 
-type ortcServerErrorOperation int
+//ServerErrorOperation identifies the operation an ortc-error frame refers to.
+type ServerErrorOperation int
 
 const (
-	unexpected ortcServerErrorOperation = iota
-	validate
-	subscribe
-	subscribe_maxSize
-	unsubscribe_maxSize
-	send_maxSize
+	ErrorOpUnexpected ServerErrorOperation = iota
+	ErrorOpValidate
+	ErrorOpSubscribe
+	ErrorOpSubscribeMaxSize
+	ErrorOpUnsubscribeMaxSize
+	ErrorOpSendMaxSize
+	ErrorOpPublish
+)
+
+//Sentinel errors reported by the ortc client. Use errors.Is to match them, since the
+//errors returned or raised on the exception channel usually wrap them with more details.
+var (
+	ErrNotConnected      = errors.New("Not connected")
+	ErrAlreadyConnected  = errors.New("Already Connected")
+	ErrAlreadyConnecting = errors.New("Already trying to connect")
+	ErrNoPermission      = errors.New("No permission")
+	ErrInvalidChannel    = errors.New("Invalid channel")
+	ErrEmptyField        = errors.New("Empty field")
+	ErrInvalidCharacters = errors.New("Invalid characters")
+	ErrMaxLength         = errors.New("Maximum length exceeded")
+	ErrAlreadySubscribed = errors.New("Already subscribed")
+	ErrNotSubscribed     = errors.New("Not subscribed")
+	ErrInvalidMessage    = errors.New("Invalid message")
+	ErrNotAuthorized     = errors.New("Not authorized")
+	ErrReconnectFailed   = errors.New("Reconnect failed")
+	ErrInvalidState      = errors.New("Invalid state transition")
+	ErrOutOfRange        = errors.New("Value out of range")
+	ErrQueueFull         = errors.New("Queue full")
+	ErrMessageExpired    = errors.New("Message expired")
+	ErrBalancer          = errors.New("Balancer request failed")
+	ErrInvalidFilter     = errors.New("Invalid filter")
 )
 
-type ortcServerErrorException struct {
-	operation ortcServerErrorOperation
-	channel   string
-	message   string
+//FieldError reports an empty or malformed input field.
+//It wraps ErrEmptyField or ErrInvalidCharacters, and also matches ErrInvalidChannel when Field is "Channel".
+type FieldError struct {
+	Field string
+	Err   error
+}
+
+func (e *FieldError) Error() string {
+	if e.Err == ErrEmptyField {
+		return fmt.Sprintf("%s is null or empty", e.Field)
+	}
+	return fmt.Sprintf("%s has invalid characters", e.Field)
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+func (e *FieldError) Is(target error) bool {
+	return target == ErrInvalidChannel && e.Field == "Channel"
+}
+
+//MaxLengthError reports a field exceeding its size Limit.
+//It matches ErrMaxLength, and also ErrInvalidChannel when Field is "Channel".
+type MaxLengthError struct {
+	Field string
+	Limit int
+}
+
+func (e *MaxLengthError) Error() string {
+	return fmt.Sprintf("%s size exceed the limit of %d characters", e.Field, e.Limit)
+}
+
+func (e *MaxLengthError) Is(target error) bool {
+	return target == ErrMaxLength || (target == ErrInvalidChannel && e.Field == "Channel")
+}
+
+//PermissionError reports that the authentication token has no permission to Operation on Channel.
+//It wraps ErrNoPermission.
+type PermissionError struct {
+	Operation string
+	Channel   string
+}
+
+func (e *PermissionError) Error() string {
+	return fmt.Sprintf("No permission found to %s to the channel %s", e.Operation, e.Channel)
+}
+
+func (e *PermissionError) Unwrap() error {
+	return ErrNoPermission
+}
+
+//ServerError is an error reported by the ortc server in an ortc-error frame.
+type ServerError struct {
+	Operation ServerErrorOperation
+	Channel   string
+	Message   string
+}
+
+func (e *ServerError) Error() string {
+	return e.Message
+}
+
+//DroppedMessageError is raised as an exception for a message of the offline queue that was never sent.
+//It wraps the reason, usually ErrQueueFull, ErrMessageExpired or ErrNotConnected.
+type DroppedMessageError struct {
+	Channel string
+	Message string
+	Err     error
+}
+
+func (e *DroppedMessageError) Error() string {
+	return fmt.Sprintf("Message to channel %s was dropped: %v", e.Channel, e.Err)
+}
+
+func (e *DroppedMessageError) Unwrap() error {
+	return e.Err
+}
+
+//PublishError reports a Publish that failed before the server acknowledged the Parts of the message.
+//It wraps the reason, such as context.DeadlineExceeded, ErrNotConnected or a *ServerError.
+type PublishError struct {
+	Channel   string
+	MessageId string
+	Parts     []int
+	Err       error
+}
+
+func (e *PublishError) Error() string {
+	return fmt.Sprintf("Publish of message %s to channel %s failed with %d parts not acknowledged: %v", e.MessageId, e.Channel, len(e.Parts), e.Err)
+}
+
+func (e *PublishError) Unwrap() error {
+	return e.Err
+}
+
+//PartError reports a Part, counted from 1, of a message of Total parts that SendREST could not send.
+//StatusCode is the status of the server response, zero if the request failed before.
+type PartError struct {
+	Part       int
+	Total      int
+	StatusCode int
+	Err        error
+}
+
+func (e *PartError) Error() string {
+	return fmt.Sprintf("Part %d of %d was not sent: %v", e.Part, e.Total, e.Err)
+}
+
+func (e *PartError) Unwrap() error {
+	return e.Err
+}
+
+//RESTSendError reports the Parts of the message MessageId to Channel that SendREST could not send.
+type RESTSendError struct {
+	Channel   string
+	MessageId string
+	Parts     []*PartError
+}
+
+func (e *RESTSendError) Error() string {
+	if len(e.Parts) == 1 {
+		return fmt.Sprintf("Message %s to channel %s was not sent: %v", e.MessageId, e.Channel, e.Parts[0])
+	}
+	return fmt.Sprintf("Message %s to channel %s was not sent: %d of %d parts failed, first: %v", e.MessageId, e.Channel,
+		len(e.Parts), e.Parts[0].Total, e.Parts[0])
+}
+
+func (e *RESTSendError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Parts))
+	for _, part := range e.Parts {
+		errs = append(errs, part)
+	}
+	return errs
+}
+
+//StateError reports an illegal transition of the client state From a state To another.
+//It wraps ErrInvalidState.
+type StateError struct {
+	From State
+	To   State
+}
+
+func (e *StateError) Error() string {
+	return fmt.Sprintf("Cannot move from state %v to %v", e.From, e.To)
+}
+
+func (e *StateError) Unwrap() error {
+	return ErrInvalidState
+}
+
+//BalancerError reports that the cluster balancer at URL gave no valid server after Attempts requests.
+//Attempts is zero when the balancer url itself is invalid. It wraps the last failure and matches ErrBalancer.
+type BalancerError struct {
+	URL      string
+	Attempts int
+	Err      error
+}
+
+func (e *BalancerError) Error() string {
+	return fmt.Sprintf("Unable to get a server from the balancer %s: %v", e.URL, e.Err)
+}
+
+func (e *BalancerError) Unwrap() error {
+	return e.Err
+}
+
+func (e *BalancerError) Is(target error) bool {
+	return target == ErrBalancer
+}
+
+//ortcError attaches a descriptive message to one of the sentinel errors.
+type ortcError struct {
+	message string
+	err     error
+}
+
+func (e *ortcError) Error() string {
+	return e.message
+}
+
+func (e *ortcError) Unwrap() error {
+	return e.err
+}
+
+func ortcAlreadyConnectedException() error {
+	return ErrAlreadyConnected
+}
+
+func ortcAuthenticationNotAuthorizedException(message string) error {
+	return &ortcError{message, ErrNotAuthorized}
+}
+
+func ortcBalancerException(url string, attempts int, err error) error {
+	return &BalancerError{url, attempts, err}
 }
 
-func ortcAlreadyConnectedException() string {
-	return "Already Connected"
+func ortcDoesNotHavePermissionException(operation, channel string) error {
+	return &PermissionError{operation, channel}
 }
 
-func ortcAuthenticationNotAuthorizedException(message string) string {
-	return message
+func ortcEmptyFieldException(field string) error {
+	return &FieldError{field, ErrEmptyField}
 }
 
-func ortcDoesNotHavePermissionException(message string) string {
-	return message
+func ortcInvalidCharactersException(field string) error {
+	return &FieldError{field, ErrInvalidCharacters}
 }
 
-func ortcEmptyFieldException(field string) string {
-	return fmt.Sprintf("%s is null or empty", field)
+func ortcInvalidFilterException(message string) error {
+	return &ortcError{message, ErrInvalidFilter}
 }
 
-func ortcInvalidCharactersException(field string) string {
-	return fmt.Sprintf("%s has invalid characters", field)
+func ortcInvalidMessageException(message string) error {
+	return &ortcError{message, ErrInvalidMessage}
 }
 
-func ortcInvalidMessageException(message string) string {
-	return message
+func ortcMaxLengthException(field string, maxValue int) error {
+	return &MaxLengthError{field, maxValue}
 }
 
-func ortcMaxLengthException(field string, maxValue int) string {
-	return fmt.Sprintf("%s size exceed the limit of %d characters", field, maxValue)
+func ortcNotConnectedException(message string) error {
+	return &ortcError{message, ErrNotConnected}
 }
 
-func ortcNotConnectedException(message string) string {
-	return message
+func ortcNotSubscribedException(channel string) error {
+	return &ortcError{fmt.Sprintf("Not subscribed to channel %s", channel), ErrNotSubscribed}
 }
 
-func ortcNotSubscribedException(channel string) string {
-	return fmt.Sprintf("Not subscribed to channel %s", channel)
+func ortcOutOfRangeException(message string) error {
+	return &ortcError{message, ErrOutOfRange}
 }
 
-func ortcSubscribedException(message string) string {
-	return message
+func ortcSubscribedException(message string) error {
+	return &ortcError{message, ErrAlreadySubscribed}
 }
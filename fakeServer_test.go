@@ -0,0 +1,160 @@
+package ortc
+
+import (
+	"fmt"
+	"github.com/gorilla/websocket"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+//fakeServer is an ortc server for the tests. It validates every connection and answers the subscribe,
+//unsubscribe and send commands, echoing sent messages to the connections subscribed to the channel.
+type fakeServer struct {
+	*httptest.Server
+
+	mu     sync.Mutex
+	conns  map[*fakeConn]bool
+	frames []string
+	//onFrame handles a frame instead of the default answer when it returns true.
+	onFrame func(conn *fakeConn, frame string) bool
+}
+
+type fakeConn struct {
+	ws   *websocket.Conn
+	wmu  sync.Mutex
+	subs map[string]bool
+}
+
+func (fc *fakeConn) write(frame string) {
+	fc.wmu.Lock()
+	defer fc.wmu.Unlock()
+	fc.ws.WriteMessage(websocket.TextMessage, []byte(frame))
+}
+
+//writeOp writes an ortc operation frame with the fields of content, given unescaped.
+func (fc *fakeConn) writeOp(op, content string) {
+	fc.write(fmt.Sprintf(`a["{\"op\":\"%s\",%s}"]`, op, strings.Replace(content, `"`, `\"`, -1)))
+}
+
+func newFakeServer(t *testing.T) *fakeServer {
+	fs := &fakeServer{conns: make(map[*fakeConn]bool)}
+	fs.Server = httptest.NewServer(http.HandlerFunc(fs.serve))
+	t.Cleanup(fs.Close)
+	return fs
+}
+
+func (fs *fakeServer) serve(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasSuffix(r.URL.Path, "/websocket") {
+		http.NotFound(w, r)
+		return
+	}
+	upgrader := websocket.Upgrader{}
+	ws, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	fc := &fakeConn{ws: ws, subs: make(map[string]bool)}
+	fs.mu.Lock()
+	fs.conns[fc] = true
+	fs.mu.Unlock()
+	defer func() {
+		fs.mu.Lock()
+		delete(fs.conns, fc)
+		fs.mu.Unlock()
+		ws.Close()
+	}()
+
+	fc.write("o")
+	for {
+		_, message, err := ws.ReadMessage()
+		if err != nil {
+			return
+		}
+		frame := strings.TrimSuffix(strings.TrimPrefix(string(message), `"`), `"`)
+		fs.mu.Lock()
+		fs.frames = append(fs.frames, frame)
+		onFrame := fs.onFrame
+		fs.mu.Unlock()
+		if onFrame != nil && onFrame(fc, frame) {
+			continue
+		}
+
+		fields := strings.SplitN(frame, ";", 6)
+		switch fields[0] {
+		case "validate":
+			fc.writeOp("ortc-validated", `"up":null,"set":1`)
+		case "subscribe", "subscribefilter", "subscribeoptions":
+			fs.mu.Lock()
+			fc.subs[fields[3]] = true
+			fs.mu.Unlock()
+			fc.writeOp("ortc-subscribed", fmt.Sprintf(`"ch":"%s"`, fields[3]))
+		case "unsubscribe":
+			fs.mu.Lock()
+			delete(fc.subs, fields[2])
+			fs.mu.Unlock()
+			fc.writeOp("ortc-unsubscribed", fmt.Sprintf(`"ch":"%s"`, fields[2]))
+		case "send":
+			fs.broadcast(fields[3], fields[5])
+		}
+	}
+}
+
+//handle sets the onFrame hook of the server.
+func (fs *fakeServer) handle(onFrame func(conn *fakeConn, frame string) bool) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	fs.onFrame = onFrame
+}
+
+//broadcast writes a message frame to the connections subscribed to channel.
+func (fs *fakeServer) broadcast(channel, message string) {
+	fs.mu.Lock()
+	var subscribers []*fakeConn
+	for fc := range fs.conns {
+		if fc.subs[channel] {
+			subscribers = append(subscribers, fc)
+		}
+	}
+	fs.mu.Unlock()
+	for _, fc := range subscribers {
+		fc.write(fmt.Sprintf(`a["{\"ch\":\"%s\",\"m\":\"%s\"}"]`, channel, message))
+	}
+}
+
+//received returns the frames received so far starting with prefix.
+func (fs *fakeServer) received(prefix string) []string {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	var frames []string
+	for _, frame := range fs.frames {
+		if strings.HasPrefix(frame, prefix) {
+			frames = append(frames, frame)
+		}
+	}
+	return frames
+}
+
+//dropConnections closes the server side of every connection.
+func (fs *fakeServer) dropConnections() {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	for fc := range fs.conns {
+		fc.ws.Close()
+	}
+}
+
+//waitFor polls condition until it holds, failing the test after a few seconds.
+func waitFor(t *testing.T, what string, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,98 @@
+package ortc
+
+import (
+	"fmt"
+	"time"
+)
+
+const heartbeat_default_time = 15 * time.Second
+const heartbeat_min_time = 10 * time.Second
+const heartbeat_max_time = 60 * time.Second
+const heartbeat_default_fails = 3
+const heartbeat_min_fails = 1
+const heartbeat_max_fails = 6
+
+//heartbeat holds the client heartbeat settings. A copy is taken for every connection.
+type heartbeat struct {
+	active   bool
+	interval time.Duration
+	fails    int
+}
+
+//validateSuffix returns the fields appended to the validate message, telling the server
+//how often the client sends heartbeats.
+func (hb heartbeat) validateSuffix() string {
+	if !hb.active {
+		return ""
+	}
+	return fmt.Sprintf(";%d;%d", int(hb.interval/time.Second), hb.fails)
+}
+
+//SetHeartbeatActive sets whether the client sends heartbeats to the server, so idle connections are kept alive.
+//The setting applies from the next connection.
+func (c *OrtcClient) SetHeartbeatActive(active bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.heartbeat.active = active
+}
+
+//SetHeartbeatTime sets the interval between the heartbeats sent by the client, in whole seconds from 10 to 60.
+//The default is 15 seconds. The setting applies from the next connection.
+func (c *OrtcClient) SetHeartbeatTime(interval time.Duration) error {
+	if interval < heartbeat_min_time || interval > heartbeat_max_time || interval%time.Second != 0 {
+		return ortcOutOfRangeException(fmt.Sprintf("Heartbeat time must be a whole number of seconds between %v and %v", heartbeat_min_time, heartbeat_max_time))
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.heartbeat.interval = interval
+	return nil
+}
+
+//SetHeartbeatFails sets how many heartbeats may go unanswered before the connection is considered lost, from 1 to 6.
+//The default is 3. The setting applies from the next connection.
+func (c *OrtcClient) SetHeartbeatFails(fails int) error {
+	if fails < heartbeat_min_fails || fails > heartbeat_max_fails {
+		return ortcOutOfRangeException(fmt.Sprintf("Heartbeat fails must be between %d and %d", heartbeat_min_fails, heartbeat_max_fails))
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.heartbeat.fails = fails
+	return nil
+}
+
+//heartBeatRoutine watches conn until it is closed. It sends the client heartbeats once the connection is
+//validated, and closes conn if nothing is received from the server for the heartbeat timeout.
+func (client *OrtcClient) heartBeatRoutine(conn *connection, hb heartbeat) {
+	interval := client.opts.heartbeatInterval
+	timeout := interval
+	if hb.active {
+		interval = hb.interval
+		timeout = hb.interval * time.Duration(hb.fails)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-conn.done:
+			return
+		case <-ticker.C:
+			if conn.sinceLastHeartBeat() > timeout {
+				client.opts.logger.Printf("ortc: no heartbeat for %v, closing connection", timeout)
+				client.connectionLost(conn, ortcNotConnectedException(fmt.Sprintf("No heartbeat received for %v", timeout)))
+				return
+			}
+			if hb.active && client.isValidated(conn) {
+				//A full write queue already keeps the connection busy, the heartbeat is skipped.
+				conn.tryWrite([]byte("\"b\""))
+			}
+		}
+	}
+}
+
+//isValidated reports whether conn is the current connection and was validated by the server.
+func (client *OrtcClient) isValidated(conn *connection) bool {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	return client.conn == conn && client.state == StateConnected
+}
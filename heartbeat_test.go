@@ -0,0 +1,90 @@
+package ortc
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateSuffix(t *testing.T) {
+	if suffix := (heartbeat{active: false, interval: 15 * time.Second, fails: 3}).validateSuffix(); suffix != "" {
+		t.Errorf("inactive heartbeat suffix = %q, want none", suffix)
+	}
+	if suffix := (heartbeat{active: true, interval: 20 * time.Second, fails: 4}).validateSuffix(); suffix != ";20;4" {
+		t.Errorf("active heartbeat suffix = %q, want ;20;4", suffix)
+	}
+}
+
+func TestHeartbeats(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	fs := newFakeServer(t)
+	c := NewClient(WithEventHandler(HandlerFuncs{}))
+	c.SetHeartbeatActive(true)
+	if err := c.SetHeartbeatTime(20 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SetHeartbeatFails(4); err != nil {
+		t.Fatal(err)
+	}
+	//Shorter than the settings allow, so the test does not wait for seconds.
+	c.mu.Lock()
+	c.heartbeat.interval = 20 * time.Millisecond
+	c.heartbeat.fails = 1000
+	c.mu.Unlock()
+	if err := c.ConnectContext(ctx, "appKey", "token", "", fs.URL, false, false); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+
+	if validate := fs.received("validate;"); len(validate) != 1 || !strings.HasSuffix(validate[0], ";0;1000") {
+		t.Fatalf("validate frames = %v, want the heartbeat settings appended", validate)
+	}
+	waitFor(t, "heartbeats", func() bool { return len(fs.received("b")) >= 3 })
+
+	//The heartbeat routine exits once the connection is closed.
+	c.mu.Lock()
+	conn, hb := c.conn, c.heartbeat
+	c.mu.Unlock()
+	stopped := make(chan struct{})
+	go func() {
+		c.heartBeatRoutine(conn, hb)
+		close(stopped)
+	}()
+	c.Disconnect()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		t.Fatal("heartbeat routine still running after Disconnect")
+	}
+	sent := len(fs.received("b"))
+	time.Sleep(100 * time.Millisecond)
+	if len(fs.received("b")) != sent {
+		t.Fatal("heartbeats sent after Disconnect")
+	}
+}
+
+func TestSilentConnectionLost(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	fs := newFakeServer(t)
+	reconnecting := make(chan error, 1)
+	c := NewClient(WithHeartbeatInterval(50*time.Millisecond), WithReconnectPolicy(fixedDelay(time.Minute)),
+		WithEventHandler(HandlerFuncs{
+			Reconnecting: func(c *OrtcClient, attempt ReconnectAttempt) { reconnecting <- nil },
+		}))
+	if err := c.ConnectContext(ctx, "appKey", "token", "", fs.URL, false, false); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer c.Disconnect()
+
+	//The fake server never sends heartbeats, so the connection is considered lost.
+	select {
+	case <-reconnecting:
+	case <-ctx.Done():
+		t.Fatal("silent connection was not closed")
+	}
+	if state := c.State(); state != StateReconnecting {
+		t.Fatalf("state = %v, want %v", state, StateReconnecting)
+	}
+}
@@ -0,0 +1,120 @@
+package ortc
+
+import (
+	"context"
+	"strings"
+)
+
+//Platform is the push notification service of a mobile device.
+type Platform string
+
+const (
+	//PlatformGCM pushes to Android devices with Google Cloud Messaging.
+	PlatformGCM Platform = "GCM"
+	//PlatformAPNS pushes to iOS devices with the Apple Push Notification service.
+	PlatformAPNS Platform = "Apns"
+)
+
+//ortcIsValidRegistration checks the registration id and platform of a push subscription.
+func ortcIsValidRegistration(registrationId string, platform Platform) error {
+	if err := ortcIsValidRegistrationId(registrationId); err != nil {
+		return err
+	} else if platform != PlatformGCM && platform != PlatformAPNS {
+		return ortcInvalidCharactersException("Platform")
+	}
+	return nil
+}
+
+func ortcIsValidRegistrationId(registrationId string) error {
+	if len(registrationId) == 0 {
+		return ortcEmptyFieldException("Registration id")
+	} else if strings.ContainsAny(registrationId, ";\"\\") {
+		return ortcInvalidCharactersException("Registration id")
+	}
+	return nil
+}
+
+//SubscribeWithNotifications subscribes the specified channel and asks the server to also forward its messages
+//as push notifications to the device with registrationId on platform. The registration is sent again when the
+//channel is subscribed on reconnect, and can be changed with UpdateRegistrationId.
+func (c *OrtcClient) SubscribeWithNotifications(channel string, subscribeOnReconnect bool, registrationId string, platform Platform) <-chan onMessageChannel {
+	err := ortcIsValidRegistration(registrationId, platform)
+	var onMessage <-chan onMessageChannel
+	if err == nil {
+		onMessage, _, err = c.startSubscribe(channel, SubscribeOptions{SubscribeOnReconnect: subscribeOnReconnect,
+			RegistrationId: registrationId, Platform: platform}, false)
+	}
+	if err != nil {
+		raiseOrtcExceptionEvent(onException, c, err)
+		return nil
+	}
+	return onMessage
+}
+
+//SubscribeWithNotificationsContext works like SubscribeWithNotifications and blocks until the server confirms the subscription.
+func (c *OrtcClient) SubscribeWithNotificationsContext(ctx context.Context, channel string, subscribeOnReconnect bool, registrationId string, platform Platform) (<-chan onMessageChannel, error) {
+	if err := ortcIsValidRegistration(registrationId, platform); err != nil {
+		return nil, err
+	}
+	return c.subscribeContext(ctx, channel, SubscribeOptions{SubscribeOnReconnect: subscribeOnReconnect,
+		RegistrationId: registrationId, Platform: platform})
+}
+
+//UpdateRegistrationId replaces the device registration id of every subscription with notifications, for instance
+//after the push service renewed it. Subscribed channels are subscribed again so the server learns the new id,
+//without raising OnSubscribed. Subscriptions still waiting for the server send the new id once they are confirmed.
+//If the server rejects the new id the channel stays subscribed with the previous one,
+//and the rejection is raised as an exception.
+func (c *OrtcClient) UpdateRegistrationId(registrationId string) error {
+	if err := ortcIsValidRegistrationId(registrationId); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	toSubscribe := make(map[string]*channelSubscription)
+	permissions := make(map[string]string)
+	var exceptions []error
+	for channelName, subscribedChannel := range c.subscribedChannels {
+		if len(subscribedChannel.registrationId) == 0 {
+			continue
+		}
+		if c.state == StateConnected && subscribedChannel.isSubscribing {
+			//The subscribe command already went out with the current id, the new one is sent once it is confirmed.
+			if len(subscribedChannel.sentRegistrationId) == 0 && subscribedChannel.registrationId != registrationId {
+				subscribedChannel.sentRegistrationId = subscribedChannel.registrationId
+			}
+			subscribedChannel.registrationId = registrationId
+			continue
+		}
+		if c.state != StateConnected || !subscribedChannel.isSubscribed {
+			//Subscriptions not confirmed yet use the new id from their next subscribe command.
+			subscribedChannel.registrationId = registrationId
+			continue
+		}
+		permission, err := c.channelHasPermissions(channelName, read)
+		if err != nil {
+			subscribedChannel.registrationId = registrationId
+			exceptions = append(exceptions, err)
+			continue
+		}
+		//The channel stays subscribed while the server confirms the new id, and keeps the previous one if it is rejected.
+		if !subscribedChannel.updatingRegistration {
+			subscribedChannel.previousRegistrationId = subscribedChannel.registrationId
+			subscribedChannel.updatingRegistration = true
+		}
+		subscribedChannel.registrationId = registrationId
+		toSubscribe[channelName] = subscribedChannel
+		permissions[channelName] = permission
+	}
+	c.mu.Unlock()
+
+	for _, err := range exceptions {
+		raiseOrtcExceptionEvent(onException, c, err)
+	}
+	for channelName, subscribedChannel := range toSubscribe {
+		if err := c.subscribe(channelName, permissions[channelName], subscribedChannel, ""); err != nil {
+			raiseOrtcExceptionEvent(onException, c, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package ortc
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func registrationIdOf(c *OrtcClient, channel string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if subscription := c.subscribedChannels[channel]; subscription != nil {
+		return subscription.registrationId
+	}
+	return ""
+}
+
+func TestUpdateRegistrationId(t *testing.T) {
+	for _, rejected := range []bool{false, true} {
+		t.Run(map[bool]string{false: "accepted", true: "rejected"}[rejected], func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			fs := newFakeServer(t)
+			fs.handle(func(fc *fakeConn, frame string) bool {
+				if rejected && strings.HasPrefix(frame, "subscribe;") && strings.Contains(frame, ";new-id;") {
+					fc.writeOp("ortc-error", `"ex":{"op":"subscribe","ch":"channel","ex":"Invalid registration id"}`)
+					return true
+				}
+				return false
+			})
+			var subscribed int32
+			exceptions := make(chan error, 1)
+			c := connectClient(t, ctx, fs, WithEventHandler(HandlerFuncs{
+				Subscribed: func(c *OrtcClient, channel string) { atomic.AddInt32(&subscribed, 1) },
+				Exception: func(c *OrtcClient, err error) {
+					select {
+					case exceptions <- err:
+					default:
+					}
+				},
+			}))
+
+			onMessage, err := c.SubscribeWithNotificationsContext(ctx, "channel", true, "old-id", PlatformGCM)
+			if err != nil {
+				t.Fatalf("subscribe: %v", err)
+			}
+			if err := c.UpdateRegistrationId("new-id"); err != nil {
+				t.Fatalf("update: %v", err)
+			}
+			waitFor(t, "the new registration id to be sent", func() bool {
+				return len(fs.received("subscribe;appKey;token;channel;;new-id;GCM")) == 1
+			})
+
+			want := "new-id"
+			if rejected {
+				want = "old-id"
+				var serverError *ServerError
+				if err := <-exceptions; !errors.As(err, &serverError) || serverError.Operation != ErrorOpSubscribe {
+					t.Fatalf("exception = %v, want a subscribe *ServerError", err)
+				}
+			}
+			waitFor(t, "the registration update to finish", func() bool {
+				c.mu.Lock()
+				defer c.mu.Unlock()
+				subscription := c.subscribedChannels["channel"]
+				return subscription != nil && !subscription.updatingRegistration
+			})
+			if id := registrationIdOf(c, "channel"); id != want {
+				t.Fatalf("registration id = %q, want %q", id, want)
+			}
+			if n := atomic.LoadInt32(&subscribed); n != 1 {
+				t.Fatalf("OnSubscribed raised %d times, want once", n)
+			}
+
+			//The subscription still works.
+			if err := c.SendContext(ctx, "channel", "hello"); err != nil {
+				t.Fatalf("send: %v", err)
+			}
+			select {
+			case msg, ok := <-onMessage:
+				if !ok || msg.Message != "hello" {
+					t.Fatalf("message = %+v, %v", msg, ok)
+				}
+			case <-ctx.Done():
+				t.Fatal("message not received")
+			}
+			if err := c.UnsubscribeContext(ctx, "channel"); err != nil {
+				t.Fatalf("unsubscribe: %v", err)
+			}
+		})
+	}
+}
+
+func TestUpdateRegistrationIdWhileSubscribing(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	fs := newFakeServer(t)
+	//The subscription with the old id is confirmed only once the id was updated.
+	release := make(chan struct{})
+	fs.handle(func(fc *fakeConn, frame string) bool {
+		if strings.HasPrefix(frame, "subscribe;") && strings.Contains(frame, ";old-id;") {
+			<-release
+		}
+		return false
+	})
+	var subscribed int32
+	c := connectClient(t, ctx, fs, WithEventHandler(HandlerFuncs{
+		Subscribed: func(c *OrtcClient, channel string) { atomic.AddInt32(&subscribed, 1) },
+	}))
+
+	if onMessage := c.SubscribeWithNotifications("channel", true, "old-id", PlatformGCM); onMessage == nil {
+		t.Fatal("subscribe failed")
+	}
+	waitFor(t, "the subscription to be sent", func() bool { return len(fs.received("subscribe;")) == 1 })
+	if err := c.UpdateRegistrationId("new-id"); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	close(release)
+
+	waitFor(t, "the new registration id to be sent", func() bool {
+		return len(fs.received("subscribe;appKey;token;channel;;new-id;GCM")) == 1
+	})
+	waitFor(t, "the registration update to finish", func() bool {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		subscription := c.subscribedChannels["channel"]
+		return subscription != nil && subscription.isSubscribed && !subscription.updatingRegistration
+	})
+	if id := registrationIdOf(c, "channel"); id != "new-id" {
+		t.Fatalf("registration id = %q, want new-id", id)
+	}
+	if n := atomic.LoadInt32(&subscribed); n != 1 {
+		t.Fatalf("OnSubscribed raised %d times, want once", n)
+	}
+	if n := len(fs.received("subscribe;")); n != 2 {
+		t.Fatalf("%d subscribe commands sent, want 2", n)
+	}
+}
@@ -0,0 +1,138 @@
+package ortc
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+//queuedMessage is a message sent while the client was not connected, waiting in the offline queue.
+type queuedMessage struct {
+	channel  string
+	message  string
+	queuedAt time.Time
+}
+
+//shouldQueue reports whether a message sent now goes to the offline queue. It must be called with client.mu held.
+//Messages are queued while connecting or reconnecting, and after connecting until the queue is flushed,
+//so they are sent in order.
+func (client *OrtcClient) shouldQueue() bool {
+	if client.opts.offlineQueueSize <= 0 {
+		return false
+	}
+	switch client.state {
+	case StateResolving, StateDialing, StateValidating, StateReconnecting:
+		return true
+	case StateConnected:
+		return client.flushing
+	}
+	return false
+}
+
+func (client *OrtcClient) isExpired(entry queuedMessage, now time.Time) bool {
+	return client.opts.offlineQueueMaxAge > 0 && now.Sub(entry.queuedAt) > client.opts.offlineQueueMaxAge
+}
+
+//enqueue adds a message to the offline queue, discarding the expired entries and, if the queue is
+//still full, the oldest one when evict is true. Otherwise it returns ErrQueueFull.
+//It must be called with client.mu held and returns the dropped messages to report.
+func (client *OrtcClient) enqueue(channel, message string, evict bool) ([]error, error) {
+	var dropped []error
+	now := time.Now()
+	for len(client.offlineQueue) > 0 && client.isExpired(client.offlineQueue[0], now) {
+		dropped = append(dropped, &DroppedMessageError{client.offlineQueue[0].channel, client.offlineQueue[0].message, ErrMessageExpired})
+		client.offlineQueue = client.offlineQueue[1:]
+	}
+	if len(client.offlineQueue) >= client.opts.offlineQueueSize {
+		if !evict {
+			return dropped, ErrQueueFull
+		}
+		dropped = append(dropped, &DroppedMessageError{client.offlineQueue[0].channel, client.offlineQueue[0].message, ErrQueueFull})
+		client.offlineQueue = client.offlineQueue[1:]
+	}
+	client.offlineQueue = append(client.offlineQueue, queuedMessage{channel, message, now})
+	return dropped, nil
+}
+
+//clearOfflineQueue discards every queued message because of err. It must be called with client.mu held
+//and returns the errors to report.
+func (client *OrtcClient) clearOfflineQueue(err error) []error {
+	var dropped []error
+	for _, entry := range client.offlineQueue {
+		dropped = append(dropped, &DroppedMessageError{entry.channel, entry.message, err})
+	}
+	client.offlineQueue = nil
+	client.flushing = false
+	return dropped
+}
+
+//startFlush starts sending the offline queue if there were queued messages when the client connected.
+func (client *OrtcClient) startFlush() {
+	client.mu.Lock()
+	flushing := client.flushing
+	conn := client.conn
+	client.mu.Unlock()
+
+	if flushing {
+		go client.flushOfflineQueue(conn)
+	}
+}
+
+//flushOfflineQueue sends the queued messages in order while conn stays the validated connection.
+func (client *OrtcClient) flushOfflineQueue(conn *connection) {
+	for {
+		client.mu.Lock()
+		if client.conn != conn {
+			client.mu.Unlock()
+			return
+		}
+		if client.state != StateConnected || len(client.offlineQueue) == 0 {
+			client.flushing = false
+			client.mu.Unlock()
+			return
+		}
+		entry := client.offlineQueue[0]
+		client.offlineQueue = client.offlineQueue[1:]
+		permission := ""
+		var err error
+		if client.isExpired(entry, time.Now()) {
+			err = ErrMessageExpired
+		} else {
+			permission, err = client.channelHasPermissions(entry.channel, write)
+		}
+		applicationKey := client.applicationKey
+		authenticationToken := client.authenticationToken
+		client.mu.Unlock()
+
+		if err == nil {
+			err = client.sendParts(context.Background(), applicationKey, authenticationToken, entry.channel, permission, entry.message, true)
+		}
+		if errors.Is(err, ErrNotConnected) && client.requeue(entry) {
+			return
+		}
+		if err != nil {
+			client.reportDropped([]error{&DroppedMessageError{entry.channel, entry.message, err}})
+		}
+	}
+}
+
+//requeue puts back at the head of the queue a message whose connection was lost while flushing, so it is sent
+//after reconnecting. It reports false if the client is closing, since the queue was discarded.
+func (client *OrtcClient) requeue(entry queuedMessage) bool {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	if client.state == StateClosing || client.state == StateClosed {
+		return false
+	}
+	client.offlineQueue = append([]queuedMessage{entry}, client.offlineQueue...)
+	return true
+}
+
+//reportDropped raises an exception for every message discarded from the offline queue.
+func (client *OrtcClient) reportDropped(dropped []error) {
+	for _, err := range dropped {
+		atomic.AddUint64(&client.dropped[StreamOfflineQueue], 1)
+		raiseOrtcExceptionEvent(onException, client, err)
+	}
+}
@@ -0,0 +1,119 @@
+package ortc
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+//reconnectingClient connects a client with an offline queue and drops its connection, returning it while reconnecting.
+func reconnectingClient(t *testing.T, ctx context.Context, fs *fakeServer, size int, maxAge time.Duration) (*OrtcClient, chan error, chan struct{}) {
+	exceptions := make(chan error, 16)
+	reconnected := make(chan struct{}, 1)
+	c := connectClient(t, ctx, fs, WithOfflineQueue(size, maxAge), WithReconnectPolicy(fixedDelay(200*time.Millisecond)),
+		WithEventHandler(HandlerFuncs{
+			Exception:   func(c *OrtcClient, err error) { exceptions <- err },
+			Reconnected: func(c *OrtcClient) { reconnected <- struct{}{} },
+		}))
+	fs.dropConnections()
+	waitFor(t, "the client to reconnect", func() bool { return c.State() == StateReconnecting })
+	return c, exceptions, reconnected
+}
+
+//sentMessages returns the messages of the send frames received by fs, in order.
+func sentMessages(fs *fakeServer) []string {
+	var messages []string
+	for _, frame := range fs.received("send;") {
+		fields := strings.SplitN(frame, ";", 6)
+		messages = append(messages, fields[5][strings.LastIndex(fields[5], "_")+1:])
+	}
+	return messages
+}
+
+func TestOfflineQueueFlushOrder(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	fs := newFakeServer(t)
+	c, _, reconnected := reconnectingClient(t, ctx, fs, 10, 0)
+
+	for _, message := range []string{"one", "two", "three"} {
+		if err := c.SendContext(ctx, "channel", message); err != nil {
+			t.Fatalf("send while reconnecting: %v", err)
+		}
+	}
+	if len(fs.received("send;")) != 0 {
+		t.Fatal("messages sent while reconnecting")
+	}
+	<-reconnected
+	waitFor(t, "the queue to be flushed", func() bool { return len(sentMessages(fs)) == 3 })
+	if sent := strings.Join(sentMessages(fs), ","); sent != "one,two,three" {
+		t.Fatalf("messages sent in order %s", sent)
+	}
+	if dropped := c.DroppedEvents(StreamOfflineQueue); dropped != 0 {
+		t.Fatalf("%d messages dropped", dropped)
+	}
+}
+
+func TestOfflineQueueDrops(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	fs := newFakeServer(t)
+	c, exceptions, reconnected := reconnectingClient(t, ctx, fs, 2, 100*time.Millisecond)
+
+	//The queue holds two messages: the oldest is evicted, and TrySend fails.
+	for _, message := range []string{"one", "two", "three"} {
+		if err := c.SendContext(ctx, "channel", message); err != nil {
+			t.Fatalf("send while reconnecting: %v", err)
+		}
+	}
+	if err := c.TrySend("channel", "four"); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("TrySend on a full queue = %v, want ErrQueueFull", err)
+	}
+	var dropped *DroppedMessageError
+	if err := <-exceptions; !errors.As(err, &dropped) || dropped.Message != "one" || !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("exception = %v, want message one dropped because the queue was full", err)
+	}
+
+	//The remaining messages expire before the client reconnects.
+	<-reconnected
+	for _, message := range []string{"two", "three"} {
+		if err := <-exceptions; !errors.As(err, &dropped) || dropped.Message != message || !errors.Is(err, ErrMessageExpired) {
+			t.Fatalf("exception = %v, want message %s expired", err, message)
+		}
+	}
+	if n := c.DroppedEvents(StreamOfflineQueue); n != 3 {
+		t.Fatalf("%d messages dropped, want 3", n)
+	}
+	if sent := sentMessages(fs); len(sent) != 0 {
+		t.Fatalf("dropped messages were sent: %v", sent)
+	}
+}
+
+func TestOfflineQueueKeptWhenFlushFails(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	fs := newFakeServer(t)
+	c := connectClient(t, ctx, fs, WithOfflineQueue(10, 0))
+
+	//The connection is lost while the queue is flushed.
+	c.mu.Lock()
+	c.offlineQueue = []queuedMessage{{"channel", "one", time.Now()}, {"channel", "two", time.Now()}}
+	c.flushing = true
+	conn := c.conn
+	c.mu.Unlock()
+	conn.close()
+	c.flushOfflineQueue(conn)
+
+	c.mu.Lock()
+	queued := len(c.offlineQueue)
+	first := c.offlineQueue[0].message
+	c.mu.Unlock()
+	if queued != 2 || first != "one" {
+		t.Fatalf("queue holds %d messages starting with %s, want both messages in order", queued, first)
+	}
+	if n := c.DroppedEvents(StreamOfflineQueue); n != 0 {
+		t.Fatalf("%d messages dropped", n)
+	}
+}
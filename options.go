@@ -0,0 +1,321 @@
+package ortc
+
+import (
+	"context"
+	"github.com/gorilla/websocket"
+	"net"
+	"net/http"
+	"time"
+)
+
+//Logger is used by the client to report diagnostic messages. *log.Logger satisfies it.
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Printf(format string, v ...interface{}) {}
+
+type options struct {
+	connectionTimeout time.Duration
+	heartbeatInterval time.Duration
+	reconnectDelay    time.Duration
+	reconnectPolicy   ReconnectPolicy
+	maxMessageSize    int
+	readLimit         int64
+	writeQueueSize    int
+	writeTimeout      time.Duration
+	publishTimeout    time.Duration
+	dialer            *websocket.Dialer
+	dialFunc          func(ctx context.Context, network, addr string) (net.Conn, error)
+	header            http.Header
+	httpClient        *http.Client
+	balancerCacheTTL  time.Duration
+	logger            Logger
+	eventBufferSize   int
+	streamBufferSizes map[EventStream]int
+	overflowPolicy    OverflowPolicy
+	eventHandler      EventHandler
+	globalMessages    bool
+
+	offlineQueueSize   int
+	offlineQueueMaxAge time.Duration
+}
+
+//Option configures an OrtcClient created with NewClient.
+type Option func(*options)
+
+func defaultOptions() options {
+	return options{
+		connectionTimeout: connection_timeout_default_value * time.Millisecond,
+		heartbeatInterval: heartBeatTimeout * time.Second,
+		reconnectDelay:    reconnect_delay_default_value * time.Millisecond,
+		maxMessageSize:    max_message_size,
+		readLimit:         read_limit_default_value,
+		writeQueueSize:    write_queue_default_size,
+		writeTimeout:      write_timeout_default_value * time.Millisecond,
+		publishTimeout:    publish_timeout_default_value,
+		balancerCacheTTL:  balancer_cache_default_ttl,
+		logger:            nopLogger{},
+		globalMessages:    true,
+	}
+}
+
+//WithConnectionTimeout sets how long the websocket handshake may take. The default is 5 seconds.
+//Timeouts that are not positive are ignored.
+func WithConnectionTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		if timeout > 0 {
+			o.connectionTimeout = timeout
+		}
+	}
+}
+
+//WithHeartbeatInterval sets how long the connection may stay silent before it is considered lost, unless
+//the client sends heartbeats, see SetHeartbeatActive. The default is 30 seconds. Intervals that are not positive are ignored.
+func WithHeartbeatInterval(interval time.Duration) Option {
+	return func(o *options) {
+		if interval > 0 {
+			o.heartbeatInterval = interval
+		}
+	}
+}
+
+//WithReconnectDelay sets the maximum delay between reconnection attempts of the default reconnect policy.
+//The default is 5 seconds. Delays that are not positive are ignored.
+func WithReconnectDelay(delay time.Duration) Option {
+	return func(o *options) {
+		if delay > 0 {
+			o.reconnectDelay = delay
+		}
+	}
+}
+
+//WithReconnectPolicy sets the policy deciding when to reconnect after the connection is lost.
+//The default is an ExponentialBackoff from one second up to the reconnect delay, without limits.
+func WithReconnectPolicy(policy ReconnectPolicy) Option {
+	return func(o *options) {
+		o.reconnectPolicy = policy
+	}
+}
+
+//WithMaxMessageSize sets the size in bytes of the parts a message is split into by Send. The default is 800.
+//Sizes that are not positive are ignored.
+func WithMaxMessageSize(size int) Option {
+	return func(o *options) {
+		if size > 0 {
+			o.maxMessageSize = size
+		}
+	}
+}
+
+//WithReadLimit sets the maximum size in bytes of a frame read from the server. The default is 64KB.
+func WithReadLimit(limit int64) Option {
+	return func(o *options) {
+		o.readLimit = limit
+	}
+}
+
+//WithWriteQueueSize sets how many messages may wait to be written to the connection. The default is 64.
+//When the queue is full Send blocks and TrySend returns ErrQueueFull. Sizes that are not positive are ignored.
+func WithWriteQueueSize(size int) Option {
+	return func(o *options) {
+		if size > 0 {
+			o.writeQueueSize = size
+		}
+	}
+}
+
+//WithWriteTimeout sets how long writing a frame may take before the connection is considered lost.
+//The default is 10 seconds. Zero means no timeout, negative timeouts are ignored.
+func WithWriteTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		if timeout >= 0 {
+			o.writeTimeout = timeout
+		}
+	}
+}
+
+//WithPublishTimeout sets how long Publish waits for the server acknowledgement when its context has
+//no deadline. The default is 10 seconds. Timeouts that are not positive are ignored.
+func WithPublishTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		if timeout > 0 {
+			o.publishTimeout = timeout
+		}
+	}
+}
+
+//WithDialer sets the websocket dialer used to connect to the ortc server.
+//If its HandshakeTimeout is zero the connection timeout is used.
+//Unless WithHTTPClient is used, its proxy, TLS configuration and dial function also apply to the REST requests.
+func WithDialer(dialer *websocket.Dialer) Option {
+	return func(o *options) {
+		o.dialer = dialer
+	}
+}
+
+//WithDialFunc sets the function opening the network connections to the ortc server, overriding the one of the dialer.
+func WithDialFunc(dial func(ctx context.Context, network, addr string) (net.Conn, error)) Option {
+	return func(o *options) {
+		o.dialFunc = dial
+	}
+}
+
+//WithHeader sets headers sent with the websocket handshake, such as User-Agent.
+func WithHeader(header http.Header) Option {
+	return func(o *options) {
+		o.header = header
+	}
+}
+
+//WithHTTPClient sets the http client of the REST requests of the client: the cluster balancer,
+//presence and authentication requests. By default it follows the websocket dialer settings
+//and each request times out after 15 seconds.
+func WithHTTPClient(client *http.Client) Option {
+	return func(o *options) {
+		o.httpClient = client
+	}
+}
+
+//WithBalancerCacheTTL sets how long the server returned by the cluster balancer is reused, for connections
+//and REST requests, before the balancer is asked again. Zero asks the balancer every time, though the known
+//servers are still used when it cannot be reached. The default is 60 seconds.
+func WithBalancerCacheTTL(ttl time.Duration) Option {
+	return func(o *options) {
+		o.balancerCacheTTL = ttl
+	}
+}
+
+//WithLogger sets the logger the client writes diagnostic messages to. By default nothing is logged.
+func WithLogger(logger Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+//WithEventHandler delivers the client events to handler instead of the event channels.
+func WithEventHandler(handler EventHandler) Option {
+	return func(o *options) {
+		o.eventHandler = handler
+	}
+}
+
+//WithEventBufferSize sets the capacity of the event channels. By default they are unbuffered.
+//Negative sizes are ignored.
+func WithEventBufferSize(size int) Option {
+	return func(o *options) {
+		if size >= 0 {
+			o.eventBufferSize = size
+		}
+	}
+}
+
+//WithStreamBufferSize sets the capacity of the event channel of stream, overriding WithEventBufferSize.
+//StreamSubscription sets the capacity of the channels returned by Subscribe, 100 by default,
+//StreamState the capacity of the StateChanges channel, 16 by default, and StreamReconnectAttempt the capacity
+//of the OnReconnectAttempt channel, 16 by default. Negative sizes are ignored.
+func WithStreamBufferSize(stream EventStream, size int) Option {
+	return func(o *options) {
+		if size < 0 {
+			return
+		}
+		if o.streamBufferSizes == nil {
+			o.streamBufferSizes = make(map[EventStream]int)
+		}
+		o.streamBufferSizes[stream] = size
+	}
+}
+
+//WithOverflowPolicy sets what happens to an event when its channel is full. The default is OverflowBlock.
+func WithOverflowPolicy(policy OverflowPolicy) Option {
+	return func(o *options) {
+		o.overflowPolicy = policy
+	}
+}
+
+//WithGlobalMessageStream sets whether received messages are delivered as OnMessage events besides
+//the channel returned by Subscribe. The default is true.
+//
+//While enabled, the channels returned by Subscribe never block the client: messages that do not fit
+//their buffer are dropped. When disabled they follow the overflow policy.
+func WithGlobalMessageStream(enabled bool) Option {
+	return func(o *options) {
+		o.globalMessages = enabled
+	}
+}
+
+//WithOfflineQueue keeps up to size messages sent while the client is connecting or reconnecting,
+//and sends them in order once the connection is validated. Messages older than maxAge are dropped,
+//zero meaning they never expire. When the queue is full the oldest message is dropped.
+//Every dropped message is reported as a DroppedMessageError exception. By default there is no queue.
+func WithOfflineQueue(size int, maxAge time.Duration) Option {
+	return func(o *options) {
+		o.offlineQueueSize = size
+		o.offlineQueueMaxAge = maxAge
+	}
+}
+
+func (o *options) streamBufferSize(stream EventStream) int {
+	if size, ok := o.streamBufferSizes[stream]; ok {
+		return size
+	}
+	if stream == StreamSubscription {
+		return subscription_buffer_default_size
+	}
+	if stream == StreamState || stream == StreamReconnectAttempt {
+		return state_buffer_default_size
+	}
+	return o.eventBufferSize
+}
+
+//subscriptionPolicy returns the overflow policy of the channels returned by Subscribe.
+func (o *options) subscriptionPolicy() OverflowPolicy {
+	if o.globalMessages && o.overflowPolicy == OverflowBlock {
+		return OverflowDropNewest
+	}
+	return o.overflowPolicy
+}
+
+//websocketDialer returns the dialer to use, applying the connection timeout when it has none.
+func (o *options) websocketDialer() *websocket.Dialer {
+	dialer := websocket.Dialer{Proxy: http.ProxyFromEnvironment}
+	if o.dialer != nil {
+		dialer = *o.dialer
+	}
+	if dialer.HandshakeTimeout == 0 {
+		dialer.HandshakeTimeout = o.connectionTimeout
+	}
+	if o.dialFunc != nil {
+		dialer.NetDialContext = o.dialFunc
+	}
+	return &dialer
+}
+
+//restClient returns the http client of the REST requests.
+func (o *options) restClient() *http.Client {
+	if o.httpClient != nil {
+		return o.httpClient
+	}
+	return httpClientFor(o.websocketDialer())
+}
+
+//defaultHTTPClient sends the REST requests of the package functions, such as GetPresence and SaveAuthentication.
+var defaultHTTPClient = &http.Client{Timeout: http_timeout_default_value * time.Millisecond}
+
+//httpClientFor returns an http client with the proxy, TLS configuration and dial function of dialer.
+func httpClientFor(dialer *websocket.Dialer) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = dialer.Proxy
+	transport.TLSClientConfig = dialer.TLSClientConfig
+	if dialer.NetDialContext != nil {
+		transport.DialContext = dialer.NetDialContext
+	} else if dialer.NetDial != nil {
+		netDial := dialer.NetDial
+		transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return netDial(network, addr)
+		}
+	}
+	return &http.Client{Transport: transport, Timeout: http_timeout_default_value * time.Millisecond}
+}
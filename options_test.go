@@ -0,0 +1,47 @@
+package ortc
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestInvalidSizeOptions(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	fs := newFakeServer(t)
+	c := connectClient(t, ctx, fs, WithMaxMessageSize(0), WithWriteQueueSize(-1), WithEventBufferSize(-1),
+		WithStreamBufferSize(StreamSubscription, -1))
+
+	if c.opts.maxMessageSize != max_message_size || c.opts.writeQueueSize != write_queue_default_size ||
+		c.opts.eventBufferSize != 0 || c.opts.streamBufferSize(StreamSubscription) != subscription_buffer_default_size {
+		t.Fatalf("invalid sizes were not ignored: %+v", c.opts)
+	}
+	if _, err := c.SubscribeContext(ctx, "channel", false); err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+	if err := c.SendContext(ctx, "channel", "hello"); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+}
+
+func TestInvalidDurationOptions(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	fs := newFakeServer(t)
+	for _, d := range []time.Duration{0, -time.Second} {
+		c := connectClient(t, ctx, fs, WithHeartbeatInterval(d), WithReconnectDelay(d), WithConnectionTimeout(d),
+			WithPublishTimeout(d), WithWriteTimeout(-time.Second))
+
+		defaults := defaultOptions()
+		if c.opts.heartbeatInterval != defaults.heartbeatInterval || c.opts.reconnectDelay != defaults.reconnectDelay ||
+			c.opts.connectionTimeout != defaults.connectionTimeout || c.opts.publishTimeout != defaults.publishTimeout ||
+			c.opts.writeTimeout != defaults.writeTimeout {
+			t.Fatalf("invalid durations %v were not ignored", d)
+		}
+		if err := c.SendContext(ctx, "channel", "hello"); err != nil {
+			t.Fatalf("send: %v", err)
+		}
+		c.Disconnect()
+	}
+}
@@ -1,6 +1,7 @@
 package ortc
 
 import (
+	"context"
 	"fmt"
 	"github.com/gorilla/websocket"
 	"math/rand"
@@ -8,6 +9,8 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -15,15 +18,17 @@ const max_message_size = 800
 const max_channel_size = 100
 const max_connection_metadata_size = 256
 const connection_timeout_default_value = 5000
+const reconnect_delay_default_value = 5000
+const read_limit_default_value = 64 * 1024
+const subscription_buffer_default_size = 100
+const write_queue_default_size = 64
+const write_timeout_default_value = 10000
+const http_timeout_default_value = 15000
+const state_buffer_default_size = 16
 const secure = "wss"
 const unsecure = "ws"
 const heartBeatTimeout = 30
 
-var lastHeartBeat time.Time
-var socket *websocket.Conn
-
-var isSocketClosed bool
-
 type channelPermission int
 
 type pair struct {
@@ -56,7 +61,7 @@ const (
 
 type exceptionOrtc struct {
 	Sender *OrtcClient
-	Err    string
+	Err    error
 }
 
 type subsOrtc struct {
@@ -64,15 +69,19 @@ type subsOrtc struct {
 	Channel string
 }
 
+//OrtcClient is a client of the Realtime Messaging service.
+//Its methods can be called concurrently from multiple goroutines.
 type OrtcClient struct {
-	onConnectedChannel    chan *OrtcClient
-	onDisconnectedChannel chan *OrtcClient
-	onExceptionChannel    chan exceptionOrtc
-	onMessageChannel      chan onMessageChannel
-	onReconnectedChannel  chan *OrtcClient
-	onReconnectingChannel chan *OrtcClient
-	onSubscribedChannel   chan subsOrtc
-	onUnsubscribedChannel chan subsOrtc
+	opts     options
+	handler  EventHandler
+	channels *channelHandler
+	balancer *balancerCache
+	dropped  [numStreams]uint64
+
+	stateChanges chan StateChange
+
+	//mu guards every field below.
+	mu sync.Mutex
 
 	clusterUrl             string
 	serverUrl              string
@@ -82,20 +91,76 @@ type OrtcClient struct {
 	authenticationToken    string
 	needsAuthentication    bool
 
-	uri               *url.URL
-	connectionTimeout int
-	id                int
-	protocol          string
+	uri      *url.URL
+	id       int
+	protocol string
+
+	state     State
+	conn      *connection
+	heartbeat heartbeat
+	//generation is incremented on every Disconnect, so pending reconnections can tell they were cancelled.
+	generation int
 
-	subscribedChannels      map[string]channelSubscription
+	//reconnectAttempt is the last reconnection attempt scheduled since the connection was lost.
+	//reconnectWait is closed to cancel the wait before it.
+	reconnectAttempt ReconnectAttempt
+	reconnectStart   time.Time
+	reconnectWait    chan struct{}
+
+	offlineQueue []queuedMessage
+	//flushing is set while the offline queue is sent after connecting.
+	flushing bool
+
+	subscribedChannels      map[string]*channelSubscription
 	channelsPermissions     map[string]string
 	multiPartMessagesBuffer map[string][]bufferedMessage
 
-	isCluster       bool
-	isConnected     bool
-	isDisconnecting bool
-	isReconnecting  bool
-	isConnecting    bool
+	connectWaiters     waiters
+	subscribeWaiters   waiters
+	unsubscribeWaiters waiters
+
+	//pendingPublishes are the published messages waiting for acknowledgement, by message id.
+	pendingPublishes map[string]*pendingPublish
+	publishWaiters   waiters
+
+	//seqIds are the sequence ids of the last message received on each channel.
+	seqIds map[string]string
+
+	isCluster bool
+}
+
+//NewClient creates a new instance of OrtcClient configured with the given options.
+//Unless an EventHandler is set, the ortc callback event channels of the client are returned by its Events method.
+func NewClient(opts ...Option) *OrtcClient {
+	c := new(OrtcClient)
+	c.opts = defaultOptions()
+	for _, opt := range opts {
+		opt(&c.opts)
+	}
+
+	c.opts.httpClient = c.opts.restClient()
+	c.balancer = newBalancerCache(c.opts.balancerCacheTTL)
+	if c.opts.reconnectPolicy == nil {
+		c.opts.reconnectPolicy = defaultReconnectPolicy(c.opts.reconnectDelay)
+	}
+	if c.opts.eventHandler != nil {
+		c.handler = c.opts.eventHandler
+	} else {
+		c.channels = newChannelHandler(&c.opts)
+		c.handler = c.channels
+	}
+	c.stateChanges = make(chan StateChange, c.opts.streamBufferSize(StreamState))
+	c.heartbeat = heartbeat{interval: heartbeat_default_time, fails: heartbeat_default_fails}
+	c.subscribedChannels = make(map[string]*channelSubscription)
+	c.channelsPermissions = make(map[string]string)
+	c.multiPartMessagesBuffer = make(map[string][]bufferedMessage)
+	c.connectWaiters = make(waiters)
+	c.subscribeWaiters = make(waiters)
+	c.unsubscribeWaiters = make(waiters)
+	c.pendingPublishes = make(map[string]*pendingPublish)
+	c.publishWaiters = make(waiters)
+	c.seqIds = make(map[string]string)
+	return c
 }
 
 //NewOrtcClient creates a new instance of OrtcClient.
@@ -104,25 +169,85 @@ func NewOrtcClient() (c *OrtcClient, onConnected <-chan *OrtcClient, onDisconnec
 	onMessage <-chan onMessageChannel, onReconnected <-chan *OrtcClient, onReconnecting <-chan *OrtcClient, onSubscribed <-chan subsOrtc,
 	onUnsubscribed <-chan subsOrtc) {
 
-	c = new(OrtcClient)
-	c.connectionTimeout = connection_timeout_default_value
-	c.onConnectedChannel = make(chan *OrtcClient)
-	c.onDisconnectedChannel = make(chan *OrtcClient)
-	c.onExceptionChannel = make(chan exceptionOrtc)
-	c.onMessageChannel = make(chan onMessageChannel)
-	c.onReconnectedChannel = make(chan *OrtcClient)
-	c.onReconnectingChannel = make(chan *OrtcClient)
-	c.onSubscribedChannel = make(chan subsOrtc)
-	c.onUnsubscribedChannel = make(chan subsOrtc)
-	c.subscribedChannels = make(map[string]channelSubscription)
-	c.channelsPermissions = make(map[string]string)
-	c.multiPartMessagesBuffer = make(map[string][]bufferedMessage)
-	return c, c.onConnectedChannel, c.onDisconnectedChannel, c.onExceptionChannel, c.onMessageChannel, c.onReconnectedChannel,
-		c.onReconnectingChannel, c.onSubscribedChannel, c.onUnsubscribedChannel
+	c = NewClient()
+	ev := c.Events()
+	return c, ev.OnConnected, ev.OnDisconnected, ev.OnException, ev.OnMessage, ev.OnReconnected,
+		ev.OnReconnecting, ev.OnSubscribed, ev.OnUnsubscribed
+}
+
+//Events returns the ortc callback event channels of the client.
+//The channels are nil if the client was created with an EventHandler.
+func (c *OrtcClient) Events() Events {
+	if c.channels == nil {
+		return Events{}
+	}
+	return c.channels.events()
+}
+
+//DroppedEvents returns how many events of stream were discarded by the overflow policy.
+func (c *OrtcClient) DroppedEvents(stream EventStream) uint64 {
+	return atomic.LoadUint64(&c.dropped[stream])
+}
+
+//LastReconnectAttempt returns the reconnection attempt scheduled last since the connection was lost.
+//Its Attempt is zero once the client is connected. Every attempt is also delivered on the
+//OnReconnectAttempt event channel, or to the OnReconnecting method of the EventHandler.
+func (c *OrtcClient) LastReconnectAttempt() ReconnectAttempt {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.reconnectAttempt
 }
 
 //Connect connects the ortc client to the url previously specified.
 func (client *OrtcClient) Connect(applicationKey, authenticationToken, metadata, serverUrl string, isCluster, needsAuthentication bool) {
+	_, err := client.startConnect(applicationKey, authenticationToken, metadata, serverUrl, isCluster, needsAuthentication, false)
+	if err != nil {
+		raiseOrtcExceptionEvent(onException, client, err)
+	}
+}
+
+//ConnectContext connects the ortc client to the specified url and blocks until the server validates the connection.
+//If ctx is done before that, the connection attempt is abandoned and the context error is returned.
+func (client *OrtcClient) ConnectContext(ctx context.Context, applicationKey, authenticationToken, metadata, serverUrl string, isCluster, needsAuthentication bool) error {
+	validated, err := client.startConnect(applicationKey, authenticationToken, metadata, serverUrl, isCluster, needsAuthentication, true)
+	if err != nil {
+		return err
+	}
+	return client.awaitConnect(ctx, validated)
+}
+
+//awaitConnect blocks until the connection attempt registered as validated finishes, or abandons it when ctx is done.
+func (client *OrtcClient) awaitConnect(ctx context.Context, validated chan error) error {
+	select {
+	case err := <-validated:
+		return err
+	case <-ctx.Done():
+		client.mu.Lock()
+		client.connectWaiters.remove("", validated)
+		client.mu.Unlock()
+		select {
+		case err := <-validated:
+			//The attempt finished while ctx was done, its outcome wins.
+			return err
+		default:
+		}
+		client.abortConnect(ctx.Err())
+		return ctx.Err()
+	}
+}
+
+//startConnect validates the connection parameters and starts connecting in a new goroutine.
+//If wait is true it also returns a channel that receives the outcome of the connection attempt.
+func (client *OrtcClient) startConnect(applicationKey, authenticationToken, metadata, serverUrl string, isCluster, needsAuthentication, wait bool) (chan error, error) {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	switch client.state {
+	case StateConnected:
+		return nil, ortcAlreadyConnectedException()
+	case StateResolving, StateDialing, StateValidating, StateReconnecting:
+		return nil, ErrAlreadyConnecting
+	}
 
 	client.applicationKey = applicationKey
 	client.authenticationToken = authenticationToken
@@ -137,210 +262,259 @@ func (client *OrtcClient) Connect(applicationKey, authenticationToken, metadata,
 		client.isCluster = false
 	}
 
-	if client.isConnected {
-		raiseOrtcExceptionEvent(onException, client, ortcAlreadyConnectedException())
-		//fmt.Printf("%s already connected", "Ortc")
-	} else if len(client.clusterUrl) == 0 && len(client.serverUrl) == 0 {
-		raiseOrtcExceptionEvent(onException, client, ortcEmptyFieldException("URL"))
-		raiseOrtcExceptionEvent(onException, client, ortcEmptyFieldException("Cluster URL"))
-		/*fmt.Printf("%s is null or empty", "URL")
-		fmt.Printf("%s is null or empty", "Cluster url")*/
+	if err := client.isConnectValid(); err != nil {
+		return nil, err
+	}
+	if err := client.setState(client.dialState(), nil); err != nil {
+		return nil, err
+	}
+	client.reconnectAttempt = ReconnectAttempt{}
+
+	var validated chan error
+	if wait {
+		validated = client.connectWaiters.add("")
+	}
+
+	go client.connect(client.generation)
+	return validated, nil
+}
+
+//abortConnect stops a connection attempt that was not validated yet.
+func (client *OrtcClient) abortConnect(cause error) {
+	client.shutdown(cause, false)
+}
+
+//isConnectValid must be called with client.mu held.
+func (client *OrtcClient) isConnectValid() error {
+	if len(client.clusterUrl) == 0 && len(client.serverUrl) == 0 {
+		return ortcEmptyFieldException("URL")
 	} else if len(client.applicationKey) == 0 {
-		raiseOrtcExceptionEvent(onException, client, ortcEmptyFieldException("Application key"))
-		//fmt.Printf("%s is null or empty", "Application key")
+		return ortcEmptyFieldException("Application key")
 	} else if len(client.authenticationToken) == 0 {
-		raiseOrtcExceptionEvent(onException, client, ortcEmptyFieldException("Authentication key"))
-		//fmt.Printf("%s is null or empty", "Authentication token")
-	} else if !client.isCluster && !ortcIsValidUrl(client.serverUrl) {
-		raiseOrtcExceptionEvent(onException, client, ortcInvalidCharactersException("URL"))
-		//fmt.Printf("%s has invalid characters", "Url")
+		return ortcEmptyFieldException("Authentication key")
+	} else if !client.isCluster && !ortcIsValidServerUrl(client.serverUrl) {
+		return ortcInvalidCharactersException("URL")
 	} else if client.isCluster && !ortcIsValidUrl(client.clusterUrl) {
-		raiseOrtcExceptionEvent(onException, client, ortcInvalidCharactersException("Cluster URL"))
-		//fmt.Printf("%s has invalid characters", "Cluster url")
+		return ortcInvalidCharactersException("Cluster URL")
 	} else if !ortcIsValidInput(client.applicationKey) {
-		raiseOrtcExceptionEvent(onException, client, ortcInvalidCharactersException("Application key"))
-		//fmt.Printf("%s has invalid characters", "Application key")
+		return ortcInvalidCharactersException("Application key")
 	} else if !ortcIsValidInput(client.authenticationToken) {
-		raiseOrtcExceptionEvent(onException, client, ortcInvalidCharactersException("Authentication token"))
-		//fmt.Printf("%s has invalid characters", "Authentication token")
+		return ortcInvalidCharactersException("Authentication token")
 	} else if len(client.announcementSubChannel) > 0 && !ortcIsValidInput(client.announcementSubChannel) {
-		raiseOrtcExceptionEvent(onException, client, ortcInvalidCharactersException("Announcement Subchannel"))
-		//fmt.Printf("%s has invalid characters", "Announcement Subchannel")
+		return ortcInvalidCharactersException("Announcement Subchannel")
 	} else if len(client.connectionMetadata) > 0 && len(client.connectionMetadata) > max_connection_metadata_size {
-		raiseOrtcExceptionEvent(onException, client, ortcMaxLengthException("Connection metadata", max_connection_metadata_size))
-		//fmt.Printf("%s size exceeds the limit of %d characters", "Connection metadata", max_connection_metadata_size)
-	} else if client.isConnecting && !client.isReconnecting {
-		raiseOrtcExceptionEvent(onException, client, ortcNotConnectedException("Already trying to connect"))
-		//fmt.Printf("%s already trying to connect", "Ortc")
-	} else {
+		return ortcMaxLengthException("Connection metadata", max_connection_metadata_size)
+	}
+	return nil
+}
 
-		go func() {
-			if !client.isReconnecting {
-				client.isConnecting = true
-			}
-			client.applicationKey = applicationKey
-			client.authenticationToken = authenticationToken
+//connect dials the ortc server and runs the read loop of the new connection until it is closed.
+//Cluster clients try the known servers of the balancer in turn. The attempt is dropped if the client
+//generation changes in the meantime.
+func (client *OrtcClient) connect(generation int) {
+	client.mu.Lock()
+	isCluster := client.isCluster
+	clusterUrl := client.clusterUrl
+	applicationKey := client.applicationKey
+	servers := []string{client.serverUrl}
+	client.mu.Unlock()
 
-			if client.isCluster {
-				clusterServer := getServerFromBalancer(client.clusterUrl, client.applicationKey)
-				client.serverUrl = clusterServer
-				client.isCluster = true
-			}
+	if isCluster {
+		var err error
+		servers, err = client.balancer.servers(client.opts.httpClient, clusterUrl, applicationKey)
+		if err != nil {
+			client.opts.logger.Printf("ortc: %v", err)
+			client.connectFailed(generation, err)
+			return
+		}
 
-			u, err := url.Parse(client.serverUrl)
-			if err != nil {
-				raiseOrtcExceptionEvent(onException, client, ortcInvalidCharactersException("URL"))
-			}
+		client.mu.Lock()
+		if client.generation != generation {
+			client.mu.Unlock()
+			return
+		}
+		if err := client.setState(StateDialing, nil); err != nil {
+			client.mu.Unlock()
+			client.opts.logger.Printf("ortc: %v", err)
+			return
+		}
+		client.mu.Unlock()
+	}
 
-			client.uri = u
+	var ws *websocket.Conn
+	var err error
+	for _, server := range servers {
+		var stale bool
+		ws, stale, err = client.dial(generation, server)
+		if stale {
+			return
+		}
+		if err == nil || !isCluster {
+			break
+		}
+		client.balancer.failed(clusterUrl, applicationKey, server)
+	}
+	if err != nil {
+		client.connectFailed(generation, err)
+		return
+	}
 
-			if "http" == client.uri.Scheme {
-				client.protocol = unsecure
-			} else {
-				client.protocol = secure
-			}
+	ws.SetReadLimit(client.opts.readLimit)
 
-			host := strings.Split(u.Host, ":")[0]
+	client.mu.Lock()
+	if client.generation != generation {
+		client.mu.Unlock()
+		ws.Close()
+		return
+	}
+	if err := client.setState(StateValidating, nil); err != nil {
+		client.mu.Unlock()
+		ws.Close()
+		client.opts.logger.Printf("ortc: %v", err)
+		return
+	}
+	conn := newConnection(ws, client.opts.writeQueueSize, client.opts.writeTimeout, func(conn *connection, err error) {
+		client.opts.logger.Printf("ortc: write: %v", err)
+		raiseOrtcExceptionEvent(onException, client, err)
+		client.connectionLost(conn, err)
+	})
+	client.conn = conn
+	hb := client.heartbeat
+	client.mu.Unlock()
+
+	go client.heartBeatRoutine(conn, hb)
+	client.readLoop(conn, hb)
+}
 
-			/*fmt.Println("u : " + u.String())
+//dial opens the websocket connection to server. It reports stale if the client generation changed.
+func (client *OrtcClient) dial(generation int, server string) (ws *websocket.Conn, stale bool, err error) {
+	client.mu.Lock()
+	if client.generation != generation {
+		client.mu.Unlock()
+		return nil, true, nil
+	}
+	client.serverUrl = server
+	socket, err := socketUrl(server, rand.Intn(1000), randString(8))
+	if err == nil {
+		client.uri = socket
+		client.protocol = socket.Scheme
+	}
+	client.mu.Unlock()
 
-			fmt.Println("Scheme : " + scheme)
-			fmt.Println("Host : " + host)*/
+	if err != nil {
+		return nil, false, ortcInvalidCharactersException("URL")
+	}
 
-			rand.Seed(time.Now().UTC().UnixNano())
+	connectionUrl := socket.String()
+	ws, _, err = client.opts.websocketDialer().Dial(connectionUrl, client.opts.header)
+	if err != nil {
+		client.opts.logger.Printf("ortc: dial %s: %v", connectionUrl, err)
+		return nil, false, ortcNotConnectedException("Could not connect. Check if the server is running correctly")
+	}
+	return ws, false, nil
+}
 
-			randomNumber := rand.Intn(1000)
-			randomNumberStr := strconv.Itoa(randomNumber)
-			randomString := randString(8)
-			connectionUrl := fmt.Sprintf("%s://%s/broadcast/%s/%s/websocket", client.protocol, host, randomNumberStr, randomString)
+//connectFailed reports a failed connection attempt and keeps reconnecting if the client was already reconnecting.
+func (client *OrtcClient) connectFailed(generation int, err error) {
+	client.mu.Lock()
+	if client.generation != generation {
+		client.mu.Unlock()
+		return
+	}
+	reconnecting := client.reconnectAttempt.Attempt > 0
+	next := StateClosed
+	if reconnecting {
+		next = StateReconnecting
+	}
+	if stateErr := client.setState(next, err); stateErr != nil {
+		client.mu.Unlock()
+		client.opts.logger.Printf("ortc: %v", stateErr)
+		return
+	}
+	if !reconnecting {
+		client.connectWaiters.resolveAll(err)
+	}
+	client.mu.Unlock()
 
-			//fmt.Println("connectionUrl : " + connectionUrl)
+	raiseOrtcExceptionEvent(onException, client, err)
+	if reconnecting {
+		raiseOrtcEvent(onReconnecting, client)
+	}
+}
 
-			c, _, err := websocket.DefaultDialer.Dial(connectionUrl, nil)
+func (client *OrtcClient) readLoop(conn *connection, hb heartbeat) {
+	for {
+		_, message, err := conn.ws.ReadMessage()
+		if err != nil {
+			if !conn.isClosed() {
+				client.opts.logger.Printf("ortc: read: %v", err)
+			}
+			client.connectionLost(conn, err)
+			return
+		}
 
-			if err != nil {
-				raiseOrtcExceptionEvent(onException, client, ortcNotConnectedException("Could not connect. Check if the server is running correctly"))
-				//fmt.Println("websocket.NewClient Error: %s\nResp:%+v", err, resp)
-				if client.isReconnecting {
-					raiseOrtcEvent(onReconnecting, client)
-				}
+		conn.touch()
+		wsMessage := string(message[:])
+		if strings.EqualFold(wsMessage, "h") {
+			continue
+		}
+
+		if strings.EqualFold(wsMessage, "o") {
+			client.mu.Lock()
+			validateMessage := fmt.Sprintf("\"validate;%s;%s;%s;%s;%s%s\"", client.applicationKey, client.authenticationToken,
+				client.announcementSubChannel, "", client.connectionMetadata, hb.validateSuffix())
+			client.mu.Unlock()
+
+			errWritesocket := conn.write(context.Background(), []byte(validateMessage))
+			if errWritesocket != nil {
+				raiseOrtcExceptionEvent(onException, client, errWritesocket)
+				client.connectionLost(conn, errWritesocket)
 				return
 			}
+			continue
+		}
 
-			socket = c
-
-			isSocketClosed = false
-
-			c.SetReadLimit(max_message_size)
-
-			for {
-				_, message, err := c.ReadMessage()
-				if err != nil {
-					//fmt.Println(err.Error())
-					if isSocketClosed {
-						return
-					}
-					//raiseOrtcExceptionEvent(onException, client, err.Error())
-					client.isReconnecting = true
-					//closeHeartBeatRoutine()
-					err = c.Close()
-
-					if err != nil {
-						//raiseOrtcExceptionEvent(onException, client, err.Error())
-					}
-
-					//closeHeartBeatRoutine()
-					//fmt.Println("asddas")
-					raiseOnDisconnected(client)
-					return
-				}
-
-				lastHeartBeat = time.Now()
-				wsMessage := string(message[:])
-				//fmt.Println("Message from WS :" + wsMessage)
-				if strings.EqualFold(wsMessage, "h") {
-					//fmt.Println("h")
-				} else {
-					if strings.EqualFold(wsMessage, "o") {
-						validateMessage := fmt.Sprintf("\"validate;%s;%s;%s;%s;%s\"", client.applicationKey, client.authenticationToken,
-							client.announcementSubChannel, "", client.connectionMetadata)
-
-						errWritesocket := c.WriteMessage(websocket.TextMessage, []byte(validateMessage))
-						if errWritesocket != nil {
-							raiseOrtcExceptionEvent(onException, client, errWritesocket.Error())
-							//closeHeartBeatRoutine()
-							err = c.Close()
-							if err != nil {
-								//raiseOrtcExceptionEvent(onException, client, err.Error())
-							}
-							//closeHeartBeatRoutine()
-							raiseOnDisconnected(client)
-							return
-						}
-					} else {
-						ortcMsg, err := parseMessage(wsMessage)
-						if err != nil {
-							client.isReconnecting = true
-							if c != nil {
-								//closeHeartBeatRoutine()
-								err = c.Close()
-								if err != nil {
-									//raiseOrtcExceptionEvent(onException, client, err.Error())
-								}
-								raiseOnDisconnected(client)
-							} else {
-								raiseOnDisconnected(client)
-							}
-							return
-						}
-
-						operation := ortcMsg.operation
-
-						switch operation {
-						case validated:
-							client.channelsPermissions = ortcMsg.getPermissions()
-							raiseOrtcEvent(onConnected, client)
-							go func() {
-								for {
-									//fmt.Println("hearbeatRoutine")
-									if client.isConnected {
-										time.Sleep(heartBeatTimeout * time.Second)
-										currentDate := time.Now()
-										diff := time.Since(lastHeartBeat).Seconds() - time.Since(currentDate).Seconds()
-										if diff > heartBeatTimeout {
-											err := c.Close()
-											if err != nil {
-												//raiseOrtcExceptionEvent(onException, client, err.Error())
-											}
-
-											raiseOnDisconnected(client)
-											return
-										}
-									} else {
-										return
-									}
-								}
-							}()
-						case subscribed:
-							raiseOrtcSubsEvent(onSubscribed, client, ortcMsg.channelSubscribed())
-						case unsubscribed:
-							raiseOrtcSubsEvent(onUnsubscribed, client, ortcMsg.channelUnsubscribed())
-						case received:
-							raiseOrtcReceivedEvent(onReceived, client, ortcMsg.messageChannel, ortcMsg.message, ortcMsg.messageId,
-								ortcMsg.messagePart, ortcMsg.messageTotalParts)
-						case errorOp:
-							onError(client, ortcMsg)
-						}
-					}
-
-				}
-			}
-		}()
+		ortcMsg, err := parseMessage(wsMessage)
+		if err != nil {
+			client.opts.logger.Printf("ortc: %v", err)
+			client.connectionLost(conn, err)
+			return
+		}
+
+		switch ortcMsg.operation {
+		case validated:
+			client.mu.Lock()
+			client.channelsPermissions = ortcMsg.getPermissions()
+			client.mu.Unlock()
+			raiseOrtcEvent(onConnected, client)
+		case subscribed:
+			raiseOrtcSubsEvent(onSubscribed, client, ortcMsg.channelSubscribed())
+		case unsubscribed:
+			raiseOrtcSubsEvent(onUnsubscribed, client, ortcMsg.channelUnsubscribed())
+		case received:
+			raiseOrtcReceivedEvent(onReceived, client, ortcMsg.messageChannel, ortcMsg.message, ortcMsg.messageId,
+				ortcMsg.messagePart, ortcMsg.messageTotalParts, ortcMsg.filtered, ortcMsg.seqId)
+		case ack:
+			onAck(client, ortcMsg)
+		case errorOp:
+			onError(client, ortcMsg)
+		}
+	}
+}
+
+//connectionLost closes conn after an unexpected failure and starts reconnecting.
+//It does nothing if conn was already closed, e.g. by Disconnect.
+func (client *OrtcClient) connectionLost(conn *connection, cause error) {
+	if !conn.close() {
+		return
 	}
+	raiseOnDisconnected(client, cause)
 }
 
 //GetUrl returns the url of the ortc client connection.
 func (client *OrtcClient) GetUrl() string {
+	client.mu.Lock()
+	defer client.mu.Unlock()
 	if client.isCluster {
 		return client.clusterUrl
 	} else {
@@ -348,10 +522,9 @@ func (client *OrtcClient) GetUrl() string {
 	}
 }
 
-func (client *OrtcClient) channelHasPermissions(channelName string, permission channelPermission) pair {
+//channelHasPermissions must be called with client.mu held.
+func (client *OrtcClient) channelHasPermissions(channelName string, permission channelPermission) (string, error) {
 	result := pair{first: true, second: ""}
-	//fmt.Println("channelHasPermissions: ")
-	//fmt.Printf("%v", client.channelsPermissions)
 	if len(client.channelsPermissions) > 0 {
 		domainChannelCharIndex := strings.Index(channelName, ":")
 		channelToValidate := channelName
@@ -369,57 +542,42 @@ func (client *OrtcClient) channelHasPermissions(channelName string, permission c
 	}
 
 	if !result.first {
+		noPermission := "send"
 		if permission == read {
-			noPermission := "subscribe"
-			errorMsg := fmt.Sprintf("No permission found to %s to the channel %s", noPermission, channelName)
-			raiseOrtcExceptionEvent(onException, client, ortcDoesNotHavePermissionException(errorMsg))
-			//fmt.Println(errorMsg)
-		} else {
-			noPermission := "send"
-			errorMsg := fmt.Sprintf("No permission found to %s to the channel %s", noPermission, channelName)
-			raiseOrtcExceptionEvent(onException, client, ortcDoesNotHavePermissionException(errorMsg))
-			//fmt.Println(errorMsg)
+			noPermission = "subscribe"
 		}
+		return "", ortcDoesNotHavePermissionException(noPermission, channelName)
 	}
 
-	return result
+	return result.second, nil
 }
 
-func (client *OrtcClient) isSendValid(channelName, message string) pair {
-	result := pair{first: true, second: ""}
+//isSendValid must be called with client.mu held.
+//It returns the permission hash for the channel.
+func (client *OrtcClient) isSendValid(channelName, message string) (string, error) {
+	if client.state != StateConnected {
+		return "", ErrNotConnected
+	} else if err := isMessageValid(channelName, message); err != nil {
+		return "", err
+	}
 
-	if !client.isConnected {
-		//fmt.Println("Raise not connected exception")
-		raiseOrtcExceptionEvent(onException, client, ortcNotConnectedException("Not connected"))
-		result.first = false
-	} else if len(channelName) == 0 {
-		//fmt.Println("Channel name is empty")
-		raiseOrtcExceptionEvent(onException, client, ortcEmptyFieldException("Channel"))
-		result.first = false
+	return client.channelHasPermissions(channelName, write)
+}
+
+func isMessageValid(channelName, message string) error {
+	if len(channelName) == 0 {
+		return ortcEmptyFieldException("Channel")
 	} else if !ortcIsValidInput(channelName) {
-		//fmt.Println("Channel name is invalid")
-		raiseOrtcExceptionEvent(onException, client, ortcInvalidCharactersException("Channel"))
-		result.first = false
+		return ortcInvalidCharactersException("Channel")
 	} else if len(message) == 0 {
-		//fmt.Println("Message empty")
-		raiseOrtcExceptionEvent(onException, client, ortcEmptyFieldException("Message"))
-		result.first = false
+		return ortcEmptyFieldException("Message")
 	} else if len(channelName) > max_channel_size {
-		//fmt.Println("Channel name too big")
-		raiseOrtcExceptionEvent(onException, client, ortcMaxLengthException("Channel", max_channel_size))
-		result.first = false
+		return ortcMaxLengthException("Channel", max_channel_size)
 	}
-
-	if result.first {
-		chPermissions := client.channelHasPermissions(channelName, write)
-		result.first = chPermissions.first
-		result.second = chPermissions.second
-	}
-
-	return result
+	return nil
 }
 
-func multiPartMessage(message, messageId string) []pairString {
+func multiPartMessage(message, messageId string, maxMessageSize int) []pairString {
 
 	messageParts := []pairString{}
 
@@ -427,10 +585,10 @@ func multiPartMessage(message, messageId string) []pairString {
 
 	totalParts := 0
 
-	if len(messageBytes)%max_message_size == 0 {
-		totalParts = len(messageBytes) / max_message_size
+	if len(messageBytes)%maxMessageSize == 0 {
+		totalParts = len(messageBytes) / maxMessageSize
 	} else {
-		totalParts = len(messageBytes)/max_message_size + 1
+		totalParts = len(messageBytes)/maxMessageSize + 1
 	}
 
 	messagePartIndex := 1
@@ -438,8 +596,8 @@ func multiPartMessage(message, messageId string) []pairString {
 	messagePartSize := 0
 
 	for currentPosition < len(messageBytes) {
-		if len(messageBytes)-currentPosition > max_message_size {
-			messagePartSize = max_message_size
+		if len(messageBytes)-currentPosition > maxMessageSize {
+			messagePartSize = maxMessageSize
 		} else {
 			messagePartSize = len(messageBytes) - currentPosition
 		}
@@ -450,8 +608,6 @@ func multiPartMessage(message, messageId string) []pairString {
 			copy(messagePartBytes, slice)
 			messagePartIdentifier := fmt.Sprintf("%s_%s-%s", messageId, strconv.Itoa(messagePartIndex), strconv.Itoa(totalParts))
 			messageParts = append(messageParts, pairString{firtsStr: messagePartIdentifier, secondStr: string(messagePartBytes)})
-			//fmt.Println("MultiPartMessage:")
-			//fmt.Printf("%v\n", messageParts)
 		}
 
 		currentPosition = currentPosition + messagePartSize
@@ -464,138 +620,322 @@ func multiPartMessage(message, messageId string) []pairString {
 
 //Send sends a message to the specified channel.
 func (client *OrtcClient) Send(channel, message string) {
-	sendValidation := client.isSendValid(channel, message)
-	//fmt.Println("Send Validation First: " + strconv.FormatBool(sendValidation.first))
-	if sendValidation.first {
-		messageId := randString(8)
-		messagesToSend := multiPartMessage(message, messageId)
-
-		for _, messageToSend := range messagesToSend {
-			client.send(channel, messageToSend.secondStr, messageToSend.firtsStr, sendValidation.second)
+	err := client.send(context.Background(), channel, message, true)
+	if err != nil {
+		raiseOrtcExceptionEvent(onException, client, err)
+	}
+}
+
+//SendContext sends a message to the specified channel.
+//It returns once every part of the message was handed to the connection, or when ctx is done.
+//If the client is connecting and has an offline queue, the message is queued and nil is returned.
+func (client *OrtcClient) SendContext(ctx context.Context, channel, message string) error {
+	return client.send(ctx, channel, message, true)
+}
+
+//TrySend sends a message to the specified channel without blocking.
+//It returns ErrQueueFull if the write queue of the connection, or the offline queue while connecting,
+//has no room for the message.
+func (client *OrtcClient) TrySend(channel, message string) error {
+	return client.send(context.Background(), channel, message, false)
+}
+
+//send sends a message, waiting for room in the write queue if block is true.
+func (client *OrtcClient) send(ctx context.Context, channel, message string, block bool) error {
+	client.mu.Lock()
+	if client.shouldQueue() {
+		err := isMessageValid(channel, message)
+		var dropped []error
+		if err == nil {
+			dropped, err = client.enqueue(channel, message, block)
 		}
+		client.mu.Unlock()
+		client.reportDropped(dropped)
+		return err
+	}
+	permission, err := client.isSendValid(channel, message)
+	applicationKey := client.applicationKey
+	authenticationToken := client.authenticationToken
+	client.mu.Unlock()
+
+	if err != nil {
+		return err
 	}
-	return
+	return client.sendParts(ctx, applicationKey, authenticationToken, channel, permission, message, block)
 }
 
-func (client *OrtcClient) send(channel, message, messagePartIdentifier, permission string) {
-	//fmt.Println("Sending message: " + message + " to channel:" + channel + " with partIdentifier: " + messagePartIdentifier + " and permission:" + permission)
-	escapedMessage := strconv.Quote(message)
-	if strings.EqualFold(escapedMessage[0:1], "\"") {
-		escapedMessage = escapedMessage[1:]
+//sendParts splits message in parts of the maximum message size and queues them together.
+func (client *OrtcClient) sendParts(ctx context.Context, applicationKey, authenticationToken, channel, permission, message string, block bool) error {
+	messageId := randString(8)
+	messagesToSend := multiPartMessage(message, messageId, client.opts.maxMessageSize)
+
+	messages := make([]string, 0, len(messagesToSend))
+	for _, messageToSend := range messagesToSend {
+		messages = append(messages, sendCommand(applicationKey, authenticationToken, channel, permission, messageToSend.firtsStr, messageToSend.secondStr))
 	}
-	if strings.EqualFold(escapedMessage[len(escapedMessage)-1:], "\"") {
-		escapedMessage = escapedMessage[0 : len(escapedMessage)-1]
+	return sendMessages(ctx, messages, client, block)
+}
+
+func sendCommand(applicationKey, authenticationToken, channel, permission, messagePartIdentifier, message string) string {
+	escapedMessage := escapeCommandField(message)
+	return fmt.Sprintf("send;%s;%s;%s;%s;%s", applicationKey, authenticationToken, channel, permission, fmt.Sprintf("%s_%s", messagePartIdentifier, escapedMessage))
+}
+
+//escapeCommandField escapes value for the quoted frame of a command.
+func escapeCommandField(value string) string {
+	escaped := strconv.Quote(value)
+	if strings.EqualFold(escaped[0:1], "\"") {
+		escaped = escaped[1:]
 	}
-	messageParsed := fmt.Sprintf("send;%s;%s;%s;%s;%s", client.applicationKey, client.authenticationToken, channel, permission, fmt.Sprintf("%s_%s", messagePartIdentifier, escapedMessage))
-	//fmt.Println("Message Parsed: " + messageParsed)
-	sendMessage(messageParsed, client)
+	if strings.EqualFold(escaped[len(escaped)-1:], "\"") {
+		escaped = escaped[0 : len(escaped)-1]
+	}
+	return escaped
 }
 
-func sendMessage(message string, c *OrtcClient) {
-	msgFinal := fmt.Sprintf("\"%s\"", message)
-	//fmt.Println(msgFinal)
-	err := socket.WriteMessage(websocket.TextMessage, []byte(msgFinal))
-	if err != nil {
-		raiseOrtcExceptionEvent(onException, c, err.Error())
-		//fmt.Println("Error sending message to socket: " + err.Error())
+func sendMessage(ctx context.Context, message string, c *OrtcClient) error {
+	return sendMessages(ctx, []string{message}, c, true)
+}
+
+//sendMessages queues messages on the connection as a single entry of its write queue.
+//If block is false it returns ErrQueueFull instead of waiting for room in the queue.
+func sendMessages(ctx context.Context, messages []string, c *OrtcClient, block bool) error {
+	frames := make([][]byte, 0, len(messages))
+	for _, message := range messages {
+		frames = append(frames, []byte(fmt.Sprintf("\"%s\"", message)))
 	}
 
+	c.mu.Lock()
+	conn := c.conn
+	c.mu.Unlock()
+
+	if conn == nil {
+		return ErrNotConnected
+	}
+	var err error
+	if block {
+		err = conn.write(ctx, frames...)
+	} else {
+		err = conn.tryWrite(frames...)
+	}
+	if err == errConnectionClosed {
+		return ErrNotConnected
+	}
+	return err
 }
 
 //Subscribe subscribes the specified channel in order to receive messages in that channel.
+//The messages of the channel are delivered on the returned channel, which is closed when the
+//channel is unsubscribed or the client disconnects.
 func (c *OrtcClient) Subscribe(channel string, subscribeOnReconnect bool) <-chan onMessageChannel {
-	subscribedChannel := c.subscribedChannels[channel]
-	subscribeValidation := isSubscribeValid(c, channel, subscribedChannel)
+	onMessage, _, err := c.startSubscribe(channel, SubscribeOptions{SubscribeOnReconnect: subscribeOnReconnect}, false)
+	if err != nil {
+		raiseOrtcExceptionEvent(onException, c, err)
+		return nil
+	}
+	return onMessage
+}
 
-	if subscribeValidation.first {
-		subscribedChannel := channelSubscription{}
-		subscribedChannel.subscribeOnReconnect = subscribeOnReconnect
-		subscribedChannel.isSubscribing = true
-		subscribedChannel.isSubscribed = false
-		subscribedChannel.onMessage = make(chan onMessageChannel)
-		c.subscribedChannels[channel] = subscribedChannel
-		c.subscribe(channel, subscribeValidation.second)
-		return subscribedChannel.onMessage
+//SubscribeContext subscribes the specified channel and blocks until the server confirms the subscription.
+//If ctx is done first the context error is returned, but the subscription request is not withdrawn.
+func (c *OrtcClient) SubscribeContext(ctx context.Context, channel string, subscribeOnReconnect bool) (<-chan onMessageChannel, error) {
+	return c.subscribeContext(ctx, channel, SubscribeOptions{SubscribeOnReconnect: subscribeOnReconnect})
+}
+
+//SubscribeWithFilter subscribes the specified channel receiving only the messages matching filter, an
+//expression on the fields of the JSON messages such as "message.a = 1 AND message.b = 'x'".
+//The messages are delivered on the returned channel with Filtered set if the server applied the filter.
+//The filter is applied again when the channel is subscribed on reconnect.
+func (c *OrtcClient) SubscribeWithFilter(channel string, subscribeOnReconnect bool, filter string) <-chan onMessageChannel {
+	err := ortcIsValidFilter(filter)
+	var onMessage <-chan onMessageChannel
+	if err == nil {
+		onMessage, _, err = c.startSubscribe(channel, SubscribeOptions{SubscribeOnReconnect: subscribeOnReconnect, Filter: filter}, false)
 	}
-	return nil
+	if err != nil {
+		raiseOrtcExceptionEvent(onException, c, err)
+		return nil
+	}
+	return onMessage
 }
 
-func (c *OrtcClient) subscribe(channel, permission string) {
-	subscribeMsg := fmt.Sprintf("subscribe;%s;%s;%s;%s", c.applicationKey, c.authenticationToken, channel, permission)
-	sendMessage(subscribeMsg, c)
+//SubscribeWithFilterContext works like SubscribeWithFilter and blocks until the server confirms the subscription.
+func (c *OrtcClient) SubscribeWithFilterContext(ctx context.Context, channel string, subscribeOnReconnect bool, filter string) (<-chan onMessageChannel, error) {
+	if err := ortcIsValidFilter(filter); err != nil {
+		return nil, err
+	}
+	return c.subscribeContext(ctx, channel, SubscribeOptions{SubscribeOnReconnect: subscribeOnReconnect, Filter: filter})
 }
 
-func isSubscribeValid(c *OrtcClient, channelName string, channel channelSubscription) pair {
-	result := pair{true, ""}
+func (c *OrtcClient) subscribeContext(ctx context.Context, channel string, options SubscribeOptions) (<-chan onMessageChannel, error) {
+	onMessage, subscribed, err := c.startSubscribe(channel, options, true)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.await(ctx, c.subscribeWaiters, channel, subscribed); err != nil {
+		return nil, err
+	}
+	return onMessage, nil
+}
 
-	if !c.isConnected {
-		raiseOrtcExceptionEvent(onException, c, ortcNotConnectedException("Not Connected"))
-		result.first = false
+func (c *OrtcClient) startSubscribe(channel string, options SubscribeOptions, wait bool) (<-chan onMessageChannel, chan error, error) {
+	c.mu.Lock()
+	permission, err := isSubscribeValid(c, channel, c.subscribedChannels[channel])
+	if err != nil {
+		c.mu.Unlock()
+		return nil, nil, err
+	}
+	previous := c.subscribedChannels[channel]
+	subscribedChannel := newChannelSubscription(options.SubscribeOnReconnect, c.opts.streamBufferSize(StreamSubscription))
+	subscribedChannel.filter = options.Filter
+	subscribedChannel.registrationId = options.RegistrationId
+	subscribedChannel.platform = options.Platform
+	subscribedChannel.isSubscribing = true
+	if len(options.SeqId) > 0 {
+		c.seqIds[channel] = options.SeqId
+	} else {
+		delete(c.seqIds, channel)
+	}
+	c.subscribedChannels[channel] = subscribedChannel
+	var subscribed chan error
+	if wait {
+		subscribed = c.subscribeWaiters.add(channel)
+	}
+	c.mu.Unlock()
+
+	if previous != nil {
+		previous.close()
+	}
+
+	if err := c.subscribe(channel, permission, subscribedChannel, options.SeqId); err != nil {
+		c.mu.Lock()
+		if c.subscribedChannels[channel] == subscribedChannel {
+			delete(c.subscribedChannels, channel)
+		}
+		if wait {
+			c.subscribeWaiters.remove(channel, subscribed)
+		}
+		c.mu.Unlock()
+		subscribedChannel.close()
+		return nil, nil, err
+	}
+	return subscribedChannel.onMessage, subscribed, nil
+}
+
+//subscribe sends the subscribe command of subscription, with its filter and push registration if it has them,
+//asking the server to replay the messages after seqId if it is not empty.
+func (c *OrtcClient) subscribe(channel, permission string, subscription *channelSubscription, seqId string) error {
+	c.mu.Lock()
+	subscribeMsg := subscribeCommand(c.applicationKey, c.authenticationToken, channel, permission, subscription, seqId)
+	c.mu.Unlock()
+	return sendMessage(context.Background(), subscribeMsg, c)
+}
+
+//isSubscribeValid must be called with c.mu held.
+//It returns the permission hash for the channel.
+func isSubscribeValid(c *OrtcClient, channelName string, channel *channelSubscription) (string, error) {
+	if c.state != StateConnected {
+		return "", ErrNotConnected
 	} else if len(channelName) == 0 {
-		raiseOrtcExceptionEvent(onException, c, ortcEmptyFieldException("Channel"))
-		result.first = false
+		return "", ortcEmptyFieldException("Channel")
 	} else if !ortcIsValidInput(channelName) {
-		raiseOrtcExceptionEvent(onException, c, ortcEmptyFieldException("Channel"))
-		result.first = false
-	} else if channel.isSubscribing {
-		raiseOrtcExceptionEvent(onException, c, ortcSubscribedException(fmt.Sprintf("Already subscribing to the channel %s", channelName)))
-		result.first = false
-	} else if channel.isSubscribed {
-		raiseOrtcExceptionEvent(onException, c, ortcSubscribedException(fmt.Sprintf("Already subscribed to the channel %s", channelName)))
-		result.first = false
+		return "", ortcInvalidCharactersException("Channel")
+	} else if channel != nil && channel.isSubscribing {
+		return "", ortcSubscribedException(fmt.Sprintf("Already subscribing to the channel %s", channelName))
+	} else if channel != nil && channel.isSubscribed {
+		return "", ortcSubscribedException(fmt.Sprintf("Already subscribed to the channel %s", channelName))
 	} else if len(channelName) > max_channel_size {
-		raiseOrtcExceptionEvent(onException, c, ortcMaxLengthException("Channel", max_channel_size))
-		result.first = false
-	}
-
-	if result.first {
-		channelPermission := c.channelHasPermissions(channelName, read)
-		result.first = channelPermission.first
-		result.second = channelPermission.second
+		return "", ortcMaxLengthException("Channel", max_channel_size)
 	}
 
-	return result
+	return c.channelHasPermissions(channelName, read)
 }
 
 //Stop receiving messages in the specified channel.
 func (c *OrtcClient) Unsubscribe(channel string) {
+	_, err := c.startUnsubscribe(channel, false)
+	if err != nil {
+		raiseOrtcExceptionEvent(onException, c, err)
+	}
+}
+
+//UnsubscribeContext stops receiving messages in the specified channel and blocks until the server confirms it.
+func (c *OrtcClient) UnsubscribeContext(ctx context.Context, channel string) error {
+	unsubscribed, err := c.startUnsubscribe(channel, true)
+	if err != nil {
+		return err
+	}
+	return c.await(ctx, c.unsubscribeWaiters, channel, unsubscribed)
+}
+
+func (c *OrtcClient) startUnsubscribe(channel string, wait bool) (chan error, error) {
+	c.mu.Lock()
 	subscribedChannel := c.subscribedChannels[channel]
+	if err := isUnsubscribeValid(c, channel, subscribedChannel); err != nil {
+		c.mu.Unlock()
+		return nil, err
+	}
+	subscribedChannel.subscribeOnReconnect = false
+	var unsubscribed chan error
+	if wait {
+		unsubscribed = c.unsubscribeWaiters.add(channel)
+	}
+	c.mu.Unlock()
 
-	if isUnsubscribeValid(c, channel, subscribedChannel) {
-		subscribedChannel.subscribeOnReconnect = false
-		unsubscribe(c, channel, true)
+	if err := unsubscribe(c, channel, true); err != nil {
+		if wait {
+			c.mu.Lock()
+			c.unsubscribeWaiters.remove(channel, unsubscribed)
+			c.mu.Unlock()
+		}
+		return nil, err
 	}
+	return unsubscribed, nil
 }
 
-func unsubscribe(c *OrtcClient, channel string, isValid bool) {
+func unsubscribe(c *OrtcClient, channel string, isValid bool) error {
 	if isValid {
+		c.mu.Lock()
 		unsubscribeMessage := fmt.Sprintf("unsubscribe;%s;%s", c.applicationKey, channel)
-		sendMessage(unsubscribeMessage, c)
+		c.mu.Unlock()
+		return sendMessage(context.Background(), unsubscribeMessage, c)
 	}
+	return nil
 }
 
-func isUnsubscribeValid(c *OrtcClient, channelName string, channel channelSubscription) bool {
-	result := true
-
-	//fmt.Printf("%v", channel)
-	if !c.isConnected {
-		raiseOrtcExceptionEvent(onException, c, ortcNotConnectedException("Not connected"))
-		result = false
-	} else if &channel == nil {
-		raiseOrtcExceptionEvent(onException, c, ortcEmptyFieldException("Channel"))
-		result = false
+//isUnsubscribeValid must be called with c.mu held.
+func isUnsubscribeValid(c *OrtcClient, channelName string, channel *channelSubscription) error {
+	if c.state != StateConnected {
+		return ErrNotConnected
+	} else if len(channelName) == 0 {
+		return ortcEmptyFieldException("Channel")
 	} else if !ortcIsValidInput(channelName) {
-		raiseOrtcExceptionEvent(onException, c, ortcInvalidCharactersException("Channel"))
-		result = false
-	} else if !channel.isSubscribed {
-		raiseOrtcExceptionEvent(onException, c, ortcNotSubscribedException(channelName))
-		result = false
+		return ortcInvalidCharactersException("Channel")
+	} else if channel == nil || !channel.isSubscribed {
+		return ortcNotSubscribedException(channelName)
 	} else if len(channelName) > max_channel_size {
-		raiseOrtcExceptionEvent(onException, c, ortcMaxLengthException("Channel", max_channel_size))
-		result = false
+		return ortcMaxLengthException("Channel", max_channel_size)
 	}
 
-	return result
+	return nil
+}
+
+//await blocks until the server confirms the operation registered in w, or ctx is done.
+func (c *OrtcClient) await(ctx context.Context, w waiters, key string, confirmed chan error) error {
+	select {
+	case err := <-confirmed:
+		return err
+	case <-ctx.Done():
+		c.mu.Lock()
+		w.remove(key, confirmed)
+		c.mu.Unlock()
+		select {
+		case err := <-confirmed:
+			return err
+		default:
+		}
+		return ctx.Err()
+	}
 }
 
 //Disconnect closes the current connection of the ortc client.
@@ -603,28 +943,54 @@ func (c *OrtcClient) Disconnect() {
 	c.disconnect()
 }
 
+//overflowDisconnect disconnects the client when an event could not be delivered under the OverflowDisconnect policy.
+func (c *OrtcClient) overflowDisconnect(stream EventStream) {
+	c.mu.Lock()
+	connected := c.state == StateConnected
+	c.mu.Unlock()
+
+	if connected {
+		c.opts.logger.Printf("ortc: event channel %d is full, disconnecting", stream)
+		c.disconnect()
+	}
+}
+
 func (c *OrtcClient) disconnect() {
-	c.isConnecting = false
-	c.isDisconnecting = true
-
-	if !c.isConnected && !c.isReconnecting {
-		raiseOrtcExceptionEvent(onException, c, ortcNotConnectedException("Not connected"))
-	} else if socket != nil {
-		c.isReconnecting = false
-		err := socket.Close()
-		if err != nil {
-			raiseOrtcExceptionEvent(onException, c, err.Error())
-		}
-		raiseOnDisconnected(c)
+	if !c.shutdown(nil, true) {
+		raiseOrtcExceptionEvent(onException, c, ErrNotConnected)
 	}
 }
 
+//shutdown moves the client to StateClosing, closes its connection and finishes in StateClosed.
+//It does nothing if the client is not connecting or connected, or if it is connected and connected is false.
+func (c *OrtcClient) shutdown(cause error, connected bool) bool {
+	c.mu.Lock()
+	if c.state == StateConnected && !connected {
+		c.mu.Unlock()
+		return false
+	}
+	if err := c.setState(StateClosing, cause); err != nil {
+		c.mu.Unlock()
+		return false
+	}
+	c.generation++
+	c.stopReconnectWait()
+	conn := c.conn
+	c.mu.Unlock()
+
+	if conn != nil {
+		conn.close()
+	}
+	raiseOnDisconnected(c, cause)
+	return true
+}
+
 func raiseOrtcEvent(ev eventEnum, c *OrtcClient) {
 	switch ev {
 	case onConnected:
 		raiseOnConnected(c)
 	case onDisconnected:
-		raiseOnDisconnected(c)
+		raiseOnDisconnected(c, nil)
 	case onReconnected:
 		raiseOnReconnected(c)
 	case onReconnecting:
@@ -641,142 +1007,269 @@ func raiseOrtcSubsEvent(ev eventEnum, c *OrtcClient, channel string) {
 	}
 }
 
-func raiseOrtcExceptionEvent(ev eventEnum, c *OrtcClient, errStr string) {
+func raiseOrtcExceptionEvent(ev eventEnum, c *OrtcClient, err error) {
 	switch ev {
 	case onException:
-		raiseOnException(c, errStr)
+		raiseOnException(c, err)
 	}
 }
 
-func raiseOrtcReceivedEvent(ev eventEnum, c *OrtcClient, msgCh, msg, msgId string, msgPart, msgTotalParts int) {
+func raiseOrtcReceivedEvent(ev eventEnum, c *OrtcClient, msgCh, msg, msgId string, msgPart, msgTotalParts int, filtered bool, seqId string) {
 	switch ev {
 	case onReceived:
-		raiseOnReceived(c, msgCh, msg, msgId, msgPart, msgTotalParts)
+		raiseOnReceived(c, msgCh, msg, msgId, msgPart, msgTotalParts, filtered, seqId)
 	}
 }
 
+//The raiseOn* functions update the client state while holding c.mu and release it
+//before delivering the event, so event consumers may call back into the client.
+
 func raiseOnConnected(c *OrtcClient) {
-	c.isConnected = true
-	c.isDisconnecting = false
-	if c.isReconnecting && !c.isConnecting {
+	c.mu.Lock()
+	if err := c.setState(StateConnected, nil); err != nil {
+		c.mu.Unlock()
+		c.opts.logger.Printf("ortc: %v", err)
+		return
+	}
+	reconnected := c.reconnectAttempt.Attempt > 0
+	c.reconnectAttempt = ReconnectAttempt{}
+	c.connectWaiters.resolveAll(nil)
+	//Messages sent from now on are queued behind the offline queue, which is flushed once the
+	//channels are resubscribed.
+	c.flushing = len(c.offlineQueue) > 0
+	c.mu.Unlock()
+
+	if reconnected {
 		raiseOrtcEvent(onReconnected, c)
 	} else {
-		c.isConnecting = false
-		if c.onConnectedChannel != nil {
-			c.onConnectedChannel <- c
-		}
+		c.startFlush()
+		c.handler.OnConnected(c)
 	}
 }
 
-func raiseOnDisconnected(c *OrtcClient) {
+//raiseOnDisconnected handles a closed connection. The client reconnects if the connection was
+//validated or was a reconnection attempt, unless it is closing.
+func raiseOnDisconnected(c *OrtcClient, cause error) {
+	c.mu.Lock()
+	previous := c.state
+	reconnect := previous == StateConnected || (previous != StateClosing && c.reconnectAttempt.Attempt > 0)
+	next := StateClosed
+	if reconnect {
+		next = StateReconnecting
+	}
+	if err := c.setState(next, cause); err != nil {
+		c.mu.Unlock()
+		c.opts.logger.Printf("ortc: %v", err)
+		return
+	}
 	c.channelsPermissions = make(map[string]string)
-	if c.isDisconnecting || c.isConnecting {
-		c.isConnected = false
-		c.isDisconnecting = false
-		c.isConnecting = false
-		c.subscribedChannels = make(map[string]channelSubscription)
-		if c.onDisconnectedChannel != nil {
-			isSocketClosed = true
-			//fmt.Println("Send disconnected")
-			c.onDisconnectedChannel <- c
-		}
-	} else {
-		if !c.isReconnecting {
-			if c.onDisconnectedChannel != nil {
-				isSocketClosed = true
-				c.onDisconnectedChannel <- c
-			}
-		}
+	notConnected := ErrNotConnected
+	c.subscribeWaiters.resolveAll(notConnected)
+	c.unsubscribeWaiters.resolveAll(notConnected)
+	var closedChannels map[string]*channelSubscription
+	var dropped []error
+	if !reconnect {
+		closedChannels = c.subscribedChannels
+		c.subscribedChannels = make(map[string]*channelSubscription)
+		c.connectWaiters.resolveAll(notConnected)
+		c.failPublishes("", notConnected)
+		dropped = c.clearOfflineQueue(notConnected)
+	}
+	c.mu.Unlock()
+
+	c.reportDropped(dropped)
+
+	for _, subscribedChannel := range closedChannels {
+		subscribedChannel.close()
+	}
+
+	if previous == StateConnected || !reconnect {
+		c.handler.OnDisconnected(c)
+	}
+	if reconnect {
 		raiseOrtcEvent(onReconnecting, c)
 	}
 }
 
-func raiseOnException(c *OrtcClient, errStr string) {
-	newException := exceptionOrtc{c, errStr}
-	c.onExceptionChannel <- newException
+func raiseOnException(c *OrtcClient, err error) {
+	c.handler.OnException(c, err)
 }
 
 func raiseOnReconnected(c *OrtcClient) {
-
-	c.isReconnecting = false
-	channelsToRemove := []string{}
-	subscribedChannelsSet := []string{}
-	for k := range c.subscribedChannels {
-		subscribedChannelsSet = append(subscribedChannelsSet, k)
-	}
-
-	for _, channelName := range subscribedChannelsSet {
-		subscribedChannel := c.subscribedChannels[channelName]
+	c.mu.Lock()
+	toSubscribe := make(map[string]string)
+	subscriptions := make(map[string]*channelSubscription)
+	seqIds := make(map[string]string)
+	var exceptions []error
+	var removed []*channelSubscription
+	for channelName, subscribedChannel := range c.subscribedChannels {
 		if subscribedChannel.subscribeOnReconnected() {
 			subscribedChannel.isSubscribing = true
 			subscribedChannel.isSubscribed = false
-			chPermission := c.channelHasPermissions(channelName, read)
-
-			if chPermission.first {
-				//fmt.Println("Subscribe " + channelName + "with permission " + chPermission.second)
-				c.subscribe(channelName, chPermission.second)
+			subscribedChannel.updatingRegistration = false
+			subscribedChannel.previousRegistrationId = ""
+			subscribedChannel.sentRegistrationId = ""
+			permission, err := c.channelHasPermissions(channelName, read)
+			if err != nil {
+				c.removeSubscription(channelName, subscribedChannel)
+				removed = append(removed, subscribedChannel)
+				exceptions = append(exceptions, err)
+			} else {
+				toSubscribe[channelName] = permission
+				subscriptions[channelName] = subscribedChannel
+				seqIds[channelName] = c.seqIds[channelName]
 			}
 		} else {
-			channelsToRemove = append(channelsToRemove, channelName)
+			delete(c.subscribedChannels, channelName)
+			removed = append(removed, subscribedChannel)
 		}
 	}
+	c.mu.Unlock()
 
-	for _, channelToRemove := range channelsToRemove {
-		delete(c.subscribedChannels, channelToRemove)
+	for _, subscribedChannel := range removed {
+		subscribedChannel.close()
 	}
-
-	if c.onReconnectedChannel != nil {
-		c.onReconnectedChannel <- c
+	for _, err := range exceptions {
+		raiseOrtcExceptionEvent(onException, c, err)
+	}
+	for channelName, permission := range toSubscribe {
+		if err := c.subscribe(channelName, permission, subscriptions[channelName], seqIds[channelName]); err != nil {
+			c.mu.Lock()
+			c.removeSubscription(channelName, subscriptions[channelName])
+			c.mu.Unlock()
+			subscriptions[channelName].close()
+			raiseOrtcExceptionEvent(onException, c, err)
+		}
 	}
+	c.startFlush()
 
-	//fmt.Println("RaiseOnReconnected")
+	c.handler.OnReconnected(c)
 }
 
-func raiseOnReconnecting(c *OrtcClient) {
-	if c.isReconnecting {
-		time.Sleep(connection_timeout_default_value * time.Millisecond)
+//removeSubscription forgets subscription to channel and its sequence id, unless the channel was subscribed again
+//meanwhile. The caller closes the subscription. It must be called with c.mu held.
+func (c *OrtcClient) removeSubscription(channel string, subscription *channelSubscription) {
+	if c.subscribedChannels[channel] != subscription {
+		return
 	}
+	subscription.isSubscribing = false
+	subscription.isSubscribed = false
+	delete(c.subscribedChannels, channel)
+	delete(c.seqIds, channel)
+}
 
-	c.isConnected = false
-	c.isDisconnecting = false
-	c.isReconnecting = true
-
-	if c.onReconnectingChannel != nil {
-		c.onReconnectingChannel <- c
+func raiseOnReconnecting(c *OrtcClient) {
+	c.mu.Lock()
+	if c.state != StateReconnecting {
+		c.mu.Unlock()
+		return
+	}
+	generation := c.generation
+	if c.reconnectAttempt.Attempt == 0 {
+		c.reconnectStart = time.Now()
+	}
+	attempt := ReconnectAttempt{Attempt: c.reconnectAttempt.Attempt + 1, Elapsed: time.Since(c.reconnectStart)}
+	delay, ok := c.opts.reconnectPolicy.NextDelay(attempt.Attempt, attempt.Elapsed)
+	if !ok {
+		c.mu.Unlock()
+		c.reconnectFailed(generation, &ReconnectError{attempt.Attempt - 1, attempt.Elapsed})
+		return
+	}
+	attempt.Delay = delay
+	c.reconnectAttempt = attempt
+	wait := make(chan struct{})
+	c.reconnectWait = wait
+	c.mu.Unlock()
+
+	c.handler.OnReconnecting(c, attempt)
+
+	timer := time.NewTimer(delay)
+	select {
+	case <-timer.C:
+	case <-wait:
+		//Disconnect was called while waiting.
+		timer.Stop()
+		return
 	}
 
-	if c.isCluster {
-		c.Connect(c.applicationKey, c.authenticationToken, c.connectionMetadata, c.clusterUrl, c.isCluster, c.needsAuthentication)
-	} else {
-		c.Connect(c.applicationKey, c.authenticationToken, c.connectionMetadata, c.serverUrl, c.isCluster, c.needsAuthentication)
+	c.mu.Lock()
+	if c.generation != generation {
+		c.mu.Unlock()
+		return
+	}
+	if c.reconnectWait == wait {
+		c.reconnectWait = nil
+	}
+	if err := c.setState(c.dialState(), nil); err != nil {
+		c.mu.Unlock()
+		c.opts.logger.Printf("ortc: %v", err)
+		return
 	}
+	c.mu.Unlock()
 
-	//fmt.Println("RaiseOnReconnecting")
+	go c.connect(generation)
 }
 
 func raiseOnSubscribed(c *OrtcClient, channel string) {
-	subscribedChannel := c.subscribedChannels[channel]
-	subscribedChannel.isSubscribed = true
-	subscribedChannel.isSubscribing = false
-	c.subscribedChannels[channel] = subscribedChannel
-
-	if c.onSubscribedChannel != nil {
-		c.onSubscribedChannel <- subsOrtc{c, channel}
+	c.mu.Lock()
+	updated := false
+	var resubscribe *channelSubscription
+	var permission string
+	var err error
+	if subscribedChannel, ok := c.subscribedChannels[channel]; ok {
+		updated = subscribedChannel.isSubscribed && subscribedChannel.updatingRegistration
+		subscribedChannel.updatingRegistration = false
+		subscribedChannel.previousRegistrationId = ""
+		subscribedChannel.isSubscribed = true
+		subscribedChannel.isSubscribing = false
+		if len(subscribedChannel.sentRegistrationId) > 0 {
+			//UpdateRegistrationId replaced the id while the subscription was pending, so the new one is sent now.
+			permission, err = c.channelHasPermissions(channel, read)
+			if err != nil {
+				subscribedChannel.registrationId = subscribedChannel.sentRegistrationId
+			} else {
+				subscribedChannel.previousRegistrationId = subscribedChannel.sentRegistrationId
+				subscribedChannel.updatingRegistration = true
+				resubscribe = subscribedChannel
+			}
+			subscribedChannel.sentRegistrationId = ""
+		}
 	}
+	c.subscribeWaiters.resolve(channel, nil)
+	c.mu.Unlock()
 
-	//fmt.Println("RaiseOnSubscribed")
+	if err != nil {
+		raiseOrtcExceptionEvent(onException, c, err)
+	}
+	if resubscribe != nil {
+		if err := c.subscribe(channel, permission, resubscribe, ""); err != nil {
+			raiseOrtcExceptionEvent(onException, c, err)
+		}
+	}
+	if updated {
+		//The server confirmed a new registration id of a channel already subscribed.
+		return
+	}
+	c.handler.OnSubscribed(c, channel)
 }
 
 func raiseOnUnsubscribed(c *OrtcClient, channel string) {
-	subscribedChannel := c.subscribedChannels[channel]
-	subscribedChannel.isSubscribed = false
-	subscribedChannel.isSubscribing = false
+	c.mu.Lock()
+	subscribedChannel, ok := c.subscribedChannels[channel]
+	if ok {
+		subscribedChannel.isSubscribed = false
+		subscribedChannel.isSubscribing = false
+		delete(c.subscribedChannels, channel)
+	}
+	delete(c.seqIds, channel)
+	c.unsubscribeWaiters.resolve(channel, nil)
+	c.mu.Unlock()
 
-	if c.onUnsubscribedChannel != nil {
-		c.onUnsubscribedChannel <- subsOrtc{c, channel}
+	if ok {
+		subscribedChannel.close()
 	}
 
-	//fmt.Println("RaiseOnUnsubscribed")
+	c.handler.OnUnsubscribed(c, channel)
 }
 
 // byMessagePart implements sort.Interface for []bufferedMessage based on
@@ -787,115 +1280,147 @@ func (a byMessagePart) Len() int           { return len(a) }
 func (a byMessagePart) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
 func (a byMessagePart) Less(i, j int) bool { return a[i].messagePart < a[j].messagePart }
 
-func raiseOnReceived(c *OrtcClient, channel, message, messageId string, messagePart, messageTotalParts int) {
+func raiseOnReceived(c *OrtcClient, channel, message, messageId string, messagePart, messageTotalParts int, filtered bool, seqId string) {
 
 	if messagePart == -1 || (messagePart == 1 && messageTotalParts == 1) {
-
+		c.mu.Lock()
 		subscription := c.subscribedChannels[channel]
-		//fmt.Printf("Raise on Received Subscription: %v", subscription)
-		if subscription.onMessage != nil {
-			unescapedStr := strings.Replace(message, "\\\\\\", "", -1)
-			//fmt.Println("UnescapedStr : " + unescapedStr)
-			c.onMessageChannel <- onMessageChannel{c, channel, unescapedStr}
-			if _, ok := c.multiPartMessagesBuffer[messageId]; ok {
-				delete(c.multiPartMessagesBuffer, messageId)
-			}
-		}
-	} else {
-		if _, ok := c.multiPartMessagesBuffer[messageId]; !ok {
-			c.multiPartMessagesBuffer[messageId] = []bufferedMessage{}
+		delete(c.multiPartMessagesBuffer, messageId)
+		if subscription != nil && len(seqId) > 0 {
+			c.seqIds[channel] = seqId
 		}
+		c.mu.Unlock()
 
-		if c.multiPartMessagesBuffer[messageId] != nil {
-			c.multiPartMessagesBuffer[messageId] = append(c.multiPartMessagesBuffer[messageId], bufferedMessage{messagePart, message})
+		if subscription != nil {
+			unescapedStr := strings.Replace(message, "\\\\\\", "", -1)
+			msg := onMessageChannel{c, channel, unescapedStr, filtered, seqId}
+			if c.opts.globalMessages {
+				raiseOnMessage(c, msg)
+			}
+			subscription.deliver(c, c.opts.subscriptionPolicy(), msg)
 		}
+		return
+	}
 
-		messageParts := c.multiPartMessagesBuffer[messageId]
-
-		if len(messageParts) == messageTotalParts {
-			sort.Sort(byMessagePart(messageParts))
-			fullMessage := ""
-			for _, part := range messageParts {
-				fullMessage = fmt.Sprintf("%s%s", fullMessage, part.content)
-			}
-			raiseOnReceived(c, channel, fullMessage, messageId, -1, -1)
+	c.mu.Lock()
+	c.multiPartMessagesBuffer[messageId] = append(c.multiPartMessagesBuffer[messageId], bufferedMessage{messagePart, message})
+	messageParts := c.multiPartMessagesBuffer[messageId]
+	complete := len(messageParts) == messageTotalParts
+	c.mu.Unlock()
+
+	if complete {
+		sort.Sort(byMessagePart(messageParts))
+		fullMessage := ""
+		for _, part := range messageParts {
+			fullMessage = fmt.Sprintf("%s%s", fullMessage, part.content)
 		}
+		raiseOnReceived(c, channel, fullMessage, messageId, -1, -1, filtered, seqId)
 	}
+}
 
-	//fmt.Println("Raise on received")
+//raiseOnMessage delivers msg to the OnMessageReceived method of the handler, or to OnMessage if it has none.
+func raiseOnMessage(c *OrtcClient, msg onMessageChannel) {
+	if handler, ok := c.handler.(MessageHandler); ok {
+		handler.OnMessageReceived(c, msg)
+	} else {
+		c.handler.OnMessage(c, msg.Channel, msg.Message)
+	}
 }
 
 func onError(c *OrtcClient, message *ortcMessage) {
-	serverError, exceptionStr := message.serverError()
-	var errorStr = ""
-	if exceptionStr != "" {
-		errorStr = exceptionStr
+	var err error
+	serverError, parseErr := message.serverError()
+	if parseErr != nil {
+		err = parseErr
 	} else {
-		errorStr = serverError.message
+		err = serverError
 	}
 
 	if serverError != nil {
-		so := serverError.operation
+		so := serverError.Operation
 		switch so {
-		case validate:
-			c.validateServerError()
-		case subscribe:
-			c.cancelSubscription(serverError.channel)
-		case subscribe_maxSize:
-			c.channelMaxSizeError(serverError.channel)
-		case unsubscribe_maxSize:
-			c.channelMaxSizeError(serverError.channel)
-		case send_maxSize:
-			c.messageMaxSize()
+		case ErrorOpValidate:
+			c.validateServerError(err)
+		case ErrorOpSubscribe:
+			c.cancelSubscription(serverError.Channel, err)
+		case ErrorOpSubscribeMaxSize:
+			c.channelMaxSizeError(serverError.Channel, err)
+		case ErrorOpUnsubscribeMaxSize:
+			c.mu.Lock()
+			c.unsubscribeWaiters.resolve(serverError.Channel, err)
+			c.mu.Unlock()
+			c.channelMaxSizeError(serverError.Channel, err)
+		case ErrorOpSendMaxSize:
+			c.shutdown(err, true)
+		case ErrorOpPublish:
+			c.mu.Lock()
+			c.failPublishes(serverError.Channel, err)
+			c.mu.Unlock()
 		}
 	}
 
-	raiseOrtcExceptionEvent(onException, c, errorStr)
-	//fmt.Println("Raise on error")
+	raiseOrtcExceptionEvent(onException, c, err)
 }
 
-func (c *OrtcClient) stopReconnecting() {
-	c.isDisconnecting = false
-	c.isReconnecting = false
+//reconnectFailed stops reconnecting when the reconnect policy gives up, as if Disconnect was called.
+func (c *OrtcClient) reconnectFailed(generation int, err error) {
+	c.mu.Lock()
+	if c.generation != generation {
+		c.mu.Unlock()
+		return
+	}
+	c.reconnectAttempt = ReconnectAttempt{}
+	c.connectWaiters.resolveAll(err)
+	c.mu.Unlock()
+
+	c.opts.logger.Printf("ortc: %v", err)
+	raiseOrtcExceptionEvent(onException, c, err)
+	c.shutdown(err, true)
 }
 
-func (c *OrtcClient) validateServerError() {
-	c.stopReconnecting()
-	//closeHeartBeatRoutine()
-	err := socket.Close()
-	if err != nil {
-		raiseOrtcExceptionEvent(onException, c, err.Error())
+//stopReconnectWait cancels a pending wait before reconnecting. It must be called with c.mu held.
+func (c *OrtcClient) stopReconnectWait() {
+	if c.reconnectWait != nil {
+		close(c.reconnectWait)
+		c.reconnectWait = nil
 	}
-	raiseOnDisconnected(c)
 }
 
-func (c *OrtcClient) cancelSubscription(channel string) {
+func (c *OrtcClient) validateServerError(err error) {
+	c.mu.Lock()
+	c.connectWaiters.resolveAll(err)
+	c.mu.Unlock()
+	c.shutdown(err, true)
+}
+
+//cancelSubscription fails a pending subscription to channel with err.
+//A subscription whose registration id update was rejected keeps its previous registration id.
+func (c *OrtcClient) cancelSubscription(channel string, err error) {
 	if len(channel) > 0 {
-		if val, ok := c.subscribedChannels[channel]; ok {
-			val.isSubscribing = false
+		c.mu.Lock()
+		subscribedChannel, ok := c.subscribedChannels[channel]
+		if ok && subscribedChannel.isSubscribed && subscribedChannel.updatingRegistration {
+			subscribedChannel.registrationId = subscribedChannel.previousRegistrationId
+			subscribedChannel.updatingRegistration = false
+			subscribedChannel.previousRegistrationId = ""
+			ok = false
+		} else if ok && !subscribedChannel.isSubscribed {
+			subscribedChannel.isSubscribing = false
+			delete(c.subscribedChannels, channel)
+			delete(c.seqIds, channel)
+		} else {
+			ok = false
 		}
-	}
-}
+		c.subscribeWaiters.resolve(channel, err)
+		c.mu.Unlock()
 
-func (c *OrtcClient) channelMaxSizeError(channel string) {
-	c.cancelSubscription(channel)
-	c.stopReconnecting()
-	//closeHeartBeatRoutine()
-	err := socket.Close()
-	if err != nil {
-		raiseOrtcExceptionEvent(onException, c, err.Error())
+		if ok {
+			subscribedChannel.close()
+		}
 	}
-
-	raiseOnDisconnected(c)
 }
 
-func (c *OrtcClient) messageMaxSize() {
-	c.stopReconnecting()
-	//closeHeartBeatRoutine()
-	err := socket.Close()
-	if err != nil {
-		raiseOrtcExceptionEvent(onException, c, err.Error())
-	}
-
-	raiseOnDisconnected(c)
+func (c *OrtcClient) channelMaxSizeError(channel string, err error) {
+	c.cancelSubscription(channel, err)
+	c.shutdown(err, true)
 }
@@ -0,0 +1,211 @@
+package ortc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func connectClient(t *testing.T, ctx context.Context, fs *fakeServer, opts ...Option) *OrtcClient {
+	t.Helper()
+	c := NewClient(append([]Option{WithEventHandler(HandlerFuncs{})}, opts...)...)
+	if err := c.ConnectContext(ctx, "appKey", "token", "", fs.URL, false, false); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(func() {
+		if c.State() == StateConnected {
+			c.Disconnect()
+		}
+	})
+	return c
+}
+
+func TestClientsDoNotInterfere(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	first := connectClient(t, ctx, newFakeServer(t))
+	second := connectClient(t, ctx, newFakeServer(t))
+
+	onMessage, err := second.SubscribeContext(ctx, "channel", false)
+	if err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+
+	//Disconnect the first client while the second one is in use.
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := second.SendContext(ctx, "channel", fmt.Sprintf("message %d", i)); err != nil {
+				t.Errorf("send: %v", err)
+			}
+		}(i)
+	}
+	first.Disconnect()
+	wg.Wait()
+
+	if state := first.State(); state != StateClosed {
+		t.Fatalf("first client state = %v, want %v", state, StateClosed)
+	}
+	if state := second.State(); state != StateConnected {
+		t.Fatalf("second client state = %v, want %v", state, StateConnected)
+	}
+	for i := 0; i < 4; i++ {
+		select {
+		case msg := <-onMessage:
+			if msg.Sender != second || msg.Channel != "channel" {
+				t.Fatalf("message delivered as %+v", msg)
+			}
+		case <-ctx.Done():
+			t.Fatalf("message %d not received", i)
+		}
+	}
+
+	if _, err := second.SubscribeContext(ctx, "other", false); err != nil {
+		t.Fatalf("subscribe after disconnecting the other client: %v", err)
+	}
+	if err := second.SendContext(ctx, "other", "hello"); err != nil {
+		t.Fatalf("send after disconnecting the other client: %v", err)
+	}
+}
+
+func TestSubscribeServerError(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	fs := newFakeServer(t)
+	fs.handle(func(fc *fakeConn, frame string) bool {
+		if strings.HasPrefix(frame, "subscribe;") {
+			fc.writeOp("ortc-error", `"ex":{"op":"subscribe","ch":"channel","ex":"Access denied"}`)
+			return true
+		}
+		return false
+	})
+	exceptions := make(chan error, 1)
+	c := connectClient(t, ctx, fs, WithEventHandler(HandlerFuncs{
+		Exception: func(c *OrtcClient, err error) {
+			select {
+			case exceptions <- err:
+			default:
+			}
+		},
+	}))
+
+	_, err := c.SubscribeContext(ctx, "channel", false)
+	var serverError *ServerError
+	if !errors.As(err, &serverError) {
+		t.Fatalf("subscribe = %v, want a *ServerError", err)
+	}
+	if serverError.Operation != ErrorOpSubscribe || serverError.Channel != "channel" || serverError.Message != "Access denied" {
+		t.Fatalf("subscribe error = %+v", serverError)
+	}
+	if err := <-exceptions; !errors.As(err, &serverError) {
+		t.Fatalf("exception = %v, want a *ServerError", err)
+	}
+	if state := c.State(); state != StateConnected {
+		t.Fatalf("state = %v, want %v", state, StateConnected)
+	}
+	if _, err := c.startUnsubscribe("channel", false); !errors.Is(err, ErrNotSubscribed) {
+		t.Fatalf("unsubscribe = %v, want ErrNotSubscribed", err)
+	}
+}
+
+func TestValidateServerError(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	fs := newFakeServer(t)
+	fs.handle(func(fc *fakeConn, frame string) bool {
+		if strings.HasPrefix(frame, "validate;") {
+			fc.writeOp("ortc-error", `"ex":{"op":"validate","ex":"Invalid connection"}`)
+			return true
+		}
+		return false
+	})
+	c := NewClient(WithEventHandler(HandlerFuncs{}))
+
+	err := c.ConnectContext(ctx, "appKey", "token", "", fs.URL, false, false)
+	var serverError *ServerError
+	if !errors.As(err, &serverError) || serverError.Operation != ErrorOpValidate {
+		t.Fatalf("connect = %v, want a validate *ServerError", err)
+	}
+	waitFor(t, "the client to close", func() bool { return c.State() == StateClosed })
+}
+
+func TestResubscribeWithoutPermission(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	fs := newFakeServer(t)
+	var validations int32
+	fs.handle(func(fc *fakeConn, frame string) bool {
+		if strings.HasPrefix(frame, "validate;") && atomic.AddInt32(&validations, 1) > 1 {
+			//The reconnection is validated without the permission of the subscribed channel.
+			fc.writeOp("ortc-validated", `"up":{"other":"hash"},"set":1`)
+			return true
+		}
+		return false
+	})
+	reconnected := make(chan struct{}, 1)
+	exceptions := make(chan error, 4)
+	c := connectClient(t, ctx, fs, WithReconnectPolicy(fixedDelay(20*time.Millisecond)), WithEventHandler(HandlerFuncs{
+		Reconnected: func(c *OrtcClient) { reconnected <- struct{}{} },
+		Exception: func(c *OrtcClient, err error) {
+			select {
+			case exceptions <- err:
+			default:
+			}
+		},
+	}))
+
+	onMessage, err := c.SubscribeContext(ctx, "channel", true)
+	if err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+	fs.dropConnections()
+	select {
+	case <-reconnected:
+	case <-ctx.Done():
+		t.Fatal("client did not reconnect")
+	}
+
+	if err := <-exceptions; !errors.Is(err, ErrNoPermission) {
+		t.Fatalf("exception = %v, want ErrNoPermission", err)
+	}
+	select {
+	case _, ok := <-onMessage:
+		if ok {
+			t.Fatal("message delivered on a subscription that was not resubscribed")
+		}
+	case <-ctx.Done():
+		t.Fatal("the subscription channel was not closed")
+	}
+	if _, err := c.SubscribeContext(ctx, "channel", true); !errors.Is(err, ErrNoPermission) {
+		t.Fatalf("subscribe again = %v, want ErrNoPermission", err)
+	}
+	if _, err := c.startUnsubscribe("channel", false); !errors.Is(err, ErrNotSubscribed) {
+		t.Fatalf("unsubscribe = %v, want ErrNotSubscribed", err)
+	}
+}
+
+func TestAwaitDoneOnConfirmation(t *testing.T) {
+	c := NewClient(WithEventHandler(HandlerFuncs{}))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	//Both the confirmation and ctx are ready, the confirmation must win whichever select case runs.
+	for i := 0; i < 50; i++ {
+		validated := make(chan error, 1)
+		validated <- nil
+		if err := c.awaitConnect(ctx, validated); err != nil {
+			t.Fatalf("awaitConnect = %v, want the validation outcome", err)
+		}
+		subscribed := make(chan error, 1)
+		subscribed <- nil
+		if err := c.await(ctx, c.subscribeWaiters, "channel", subscribed); err != nil {
+			t.Fatalf("await = %v, want the subscription outcome", err)
+		}
+	}
+}
@@ -2,7 +2,6 @@ package ortc
 
 import (
 	"encoding/json"
-	"errors"
 	//"fmt"
 	"regexp"
 	"strconv"
@@ -12,6 +11,7 @@ import (
 const operation_pattern = `^a\["{\\"op\\":\\"([^\"]+)\\",(.*)\}"\]$`
 const channel_pattern = `^\\"ch\\":\\"(.*)\\"$`
 const received_pattern = `^a\["{\\"ch\\":\\"([^\"]+)\\",\\"m\\":\\"([\s\S]*?)\\"}"]$`
+const received_options_pattern = `^a\["{\\"ch\\":\\"([^\"]+)\\",(?:\\"f\\":(true|false),)?(?:\\"s\\":\\"([^\"]*)\\",)?\\"m\\":\\"([\s\S]*?)\\"}"]$`
 const multi_part_message_pattern = `^(.[^_]*)_(.[^-]*)-(.[^_]*)_([\s\S]*?)$`
 const exception_pattern = `^\\"ex\\":(\{.*\})$`
 const permissions_pattern = `^\\"up\\":{1}(.*),\\"set\\":(.*)$`
@@ -24,10 +24,26 @@ const (
 	unsubscribed
 	errorOp
 	received
+	ack
 )
 
-var operationIndex = make(map[string]ortcOperation)
-var errorOperationIndex = make(map[string]ortcServerErrorOperation)
+var operationIndex = map[string]ortcOperation{
+	"ortc-validated":    validated,
+	"ortc-subscribed":   subscribed,
+	"ortc-unsubscribed": unsubscribed,
+	"ortc-error":        errorOp,
+	"ortc-ack":          ack,
+}
+
+var errorOperationIndex = map[string]ServerErrorOperation{
+	"ex":                  ErrorOpUnexpected,
+	"validate":            ErrorOpValidate,
+	"subscribe":           ErrorOpSubscribe,
+	"subscribe_maxsize":   ErrorOpSubscribeMaxSize,
+	"unsubscribe_maxsize": ErrorOpUnsubscribeMaxSize,
+	"send_maxsize":        ErrorOpSendMaxSize,
+	"publish":             ErrorOpPublish,
+}
 
 type ortcMessage struct {
 	operation         ortcOperation
@@ -36,6 +52,10 @@ type ortcMessage struct {
 	messageId         string
 	messagePart       int
 	messageTotalParts int
+	//filtered is set on the messages of a filtered subscription when the server applied the filter.
+	filtered bool
+	//seqId is the sequence id of a message buffered by the server.
+	seqId string
 }
 
 func newOrtcMessage(operation ortcOperation, message, messageChannel, messageId string, messagePart, messageTotalParts int) *ortcMessage {
@@ -99,22 +119,12 @@ func parseMessage(message string) (*ortcMessage, error) {
 
 	//fmt.Println("parseMessage: " + message)
 
-	operationIndex["ortc-validated"] = validated
-	operationIndex["ortc-subscribed"] = subscribed
-	operationIndex["ortc-unsubscribed"] = unsubscribed
-	operationIndex["ortc-error"] = errorOp
-
-	errorOperationIndex["ex"] = unexpected
-	errorOperationIndex["validate"] = validate
-	errorOperationIndex["subscribe"] = subscribe
-	errorOperationIndex["subscribe_maxsize"] = subscribe_maxSize
-	errorOperationIndex["unsubscribe_maxsize"] = unsubscribe_maxSize
-	errorOperationIndex["send_maxsize"] = send_maxSize
-
 	var operation ortcOperation
 	var parsedMessage string
 	var messageChannel string
 	var messageId string
+	var filtered bool
+	var seqId string
 	messagePart := -1
 	messageTotalParts := -1
 
@@ -131,7 +141,8 @@ func parseMessage(message string) (*ortcMessage, error) {
 		//fmt.Println("Parsed Message: " + parsedMessage)
 	} else {
 		matcher = regexp.MustCompile(received_pattern)
-		if matcher.MatchString(message) {
+		optionsMatcher := regexp.MustCompile(received_options_pattern)
+		if matcher.MatchString(message) || optionsMatcher.MatchString(message) {
 
 			operation = received
 
@@ -141,7 +152,14 @@ func parseMessage(message string) (*ortcMessage, error) {
 
 			/*fmt.Printf("Submatches %v", stringSubMatches)*/
 
-			parsedMessage = stringSubMatches[2]
+			if stringSubMatches != nil {
+				parsedMessage = stringSubMatches[2]
+			} else {
+				stringSubMatches = optionsMatcher.FindStringSubmatch(message)
+				filtered = stringSubMatches[2] == "true"
+				seqId = stringSubMatches[3]
+				parsedMessage = stringSubMatches[4]
+			}
 
 			messageChannel = stringSubMatches[1]
 
@@ -154,7 +172,7 @@ func parseMessage(message string) (*ortcMessage, error) {
 
 				if len(subMatches[2]) > 0 {
 					var err error
-					messagePart, err = strconv.Atoi(subMatches[1])
+					messagePart, err = strconv.Atoi(subMatches[2])
 					if err != nil {
 						messagePart = -1
 					}
@@ -172,39 +190,49 @@ func parseMessage(message string) (*ortcMessage, error) {
 				}
 			}
 		} else {
-			return nil, errors.New(ortcInvalidMessageException("Invalid message format: " + message))
+			return nil, ortcInvalidMessageException("Invalid message format: " + message)
 		}
 	}
 
 	//fmt.Println("New Ortc Msg: parsedMessage:" + parsedMessage + " messageChannel: " + messageChannel + "messageId:" + messageId)
 	newMsg := newOrtcMessage(operation, parsedMessage, messageChannel, messageId, messagePart, messageTotalParts)
+	newMsg.filtered = filtered
+	newMsg.seqId = seqId
 	return newMsg, nil
 }
 
-func (o *ortcMessage) serverError() (*ortcServerErrorException, string) {
+//serverErrorPayload is the "ex" object of an ortc-error frame.
+type serverErrorPayload struct {
+	Operation string `json:"op"`
+	Channel   string `json:"ch"`
+	Message   string `json:"ex"`
+}
+
+//serverError parses the exception carried by an ortc-error frame.
+//Unknown operations are reported as ErrorOpUnexpected.
+func (o *ortcMessage) serverError() (*ServerError, error) {
 	matcher := regexp.MustCompile(exception_pattern)
 
 	if !matcher.MatchString(o.message) {
-		return nil, "Exception match not found"
+		return nil, ortcInvalidMessageException("Exception match not found")
 	}
 
 	stringSubMatches := matcher.FindStringSubmatch(o.message)
 	content := strings.Replace(stringSubMatches[1], "\\\"", "\"", -1)
 
-	jsonObj, errorJson := json.Marshal(content)
-
-	if errorJson != nil {
-		return nil, "Error marshall server error"
-	}
-
-	var m *ortcServerErrorException
+	var payload serverErrorPayload
 
-	err := json.Unmarshal(jsonObj, &m)
+	err := json.Unmarshal([]byte(content), &payload)
 
 	if err != nil {
-		return nil, "Error unmarshall server error"
+		return nil, ortcInvalidMessageException("Error unmarshall server error")
 	}
 
-	return m, ""
+	serverError := &ServerError{
+		Operation: errorOperationIndex[payload.Operation],
+		Channel:   payload.Channel,
+		Message:   payload.Message,
+	}
 
+	return serverError, nil
 }
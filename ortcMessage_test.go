@@ -0,0 +1,56 @@
+package ortc
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestServerError(t *testing.T) {
+	tests := []struct {
+		op        string
+		channel   string
+		operation ServerErrorOperation
+	}{
+		{"ex", "", ErrorOpUnexpected},
+		{"validate", "", ErrorOpValidate},
+		{"subscribe", "channel", ErrorOpSubscribe},
+		{"subscribe_maxsize", "channel", ErrorOpSubscribeMaxSize},
+		{"unsubscribe_maxsize", "channel", ErrorOpUnsubscribeMaxSize},
+		{"send_maxsize", "channel", ErrorOpSendMaxSize},
+		{"publish", "channel", ErrorOpPublish},
+		{"unknown", "channel", ErrorOpUnexpected},
+	}
+	for _, tt := range tests {
+		frame := fmt.Sprintf(`a["{\"op\":\"ortc-error\",\"ex\":{\"op\":\"%s\",\"ch\":\"%s\",\"ex\":\"failed %s\"}}"]`, tt.op, tt.channel, tt.op)
+		message, err := parseMessage(frame)
+		if err != nil {
+			t.Fatalf("%s: parseMessage: %v", tt.op, err)
+		}
+		if message.operation != errorOp {
+			t.Fatalf("%s: operation = %v, want errorOp", tt.op, message.operation)
+		}
+		serverError, err := message.serverError()
+		if err != nil {
+			t.Fatalf("%s: serverError: %v", tt.op, err)
+		}
+		if serverError.Operation != tt.operation || serverError.Channel != tt.channel || serverError.Message != "failed "+tt.op {
+			t.Errorf("%s: got %+v", tt.op, serverError)
+		}
+
+		var target *ServerError
+		if !errors.As(fmt.Errorf("wrapped: %w", serverError), &target) || target != serverError {
+			t.Errorf("%s: errors.As does not find the *ServerError", tt.op)
+		}
+	}
+}
+
+func TestServerErrorInvalid(t *testing.T) {
+	message, err := parseMessage(`a["{\"op\":\"ortc-error\",\"ex\":\"not an object\"}"]`)
+	if err != nil {
+		t.Fatalf("parseMessage: %v", err)
+	}
+	if _, err := message.serverError(); !errors.Is(err, ErrInvalidMessage) {
+		t.Fatalf("serverError = %v, want ErrInvalidMessage", err)
+	}
+}
@@ -36,25 +36,38 @@ type httpResponse struct {
 }
 
 //Gets the subscriptions in the specified channel and if active the first 100 unique metadata.
-//If success writes the result to the callback channel.
-//An error is returned if there was an error on the request.
+// If success writes the result to the callback channel.
+// An error is returned if there was an error on the request.
 func GetPresence(url string, isCluster bool, applicationKey string, authenticationToken string, channel string, callback chan<- PresenceStruct) {
-	presenceUrl := getServerFromBalancer(url, applicationKey)
-	result := getPresence(presenceUrl, isCluster, applicationKey, authenticationToken, channel, callback)
+	requestPresence(defaultHTTPClient, defaultBalancerCache, url, isCluster, applicationKey, authenticationToken, channel, callback)
+}
+
+//GetPresence works like the GetPresence function, sending the requests with the http client of c.
+func (c *OrtcClient) GetPresence(url string, isCluster bool, applicationKey string, authenticationToken string, channel string, callback chan<- PresenceStruct) {
+	requestPresence(c.opts.httpClient, c.balancer, url, isCluster, applicationKey, authenticationToken, channel, callback)
+}
+
+func requestPresence(httpClient *http.Client, balancer *balancerCache, url string, isCluster bool, applicationKey string, authenticationToken string, channel string, callback chan<- PresenceStruct) {
+	presenceUrl, err := serverUrl(httpClient, balancer, url, isCluster, applicationKey)
+	if err != nil {
+		callback <- PresenceStruct{err, presence{}}
+		return
+	}
+	result := getPresence(httpClient, presenceUrl, isCluster, applicationKey, authenticationToken, channel, callback)
 	if result.err != nil {
 		callback <- PresenceStruct{result.err, presence{}}
 	} else {
 		defer result.response.Body.Close()
 		contents, err := ioutil.ReadAll(result.response.Body)
 		if err != nil {
-			fmt.Printf("%s", err)
+			callback <- PresenceStruct{err, presence{}}
 			return
 		}
 		callback <- PresenceStruct{nil, deserialize(string(contents[:]))}
 	}
 }
 
-func getPresence(url string, isCluster bool, applicationKey string, authenticationToken string, channel string, callback chan<- PresenceStruct) *httpResponse {
+func getPresence(httpClient *http.Client, url string, isCluster bool, applicationKey string, authenticationToken string, channel string, callback chan<- PresenceStruct) *httpResponse {
 	presenceUrl := ""
 	if len(url) > 0 {
 		if url[len(url)-1] == '/' {
@@ -66,7 +79,7 @@ func getPresence(url string, isCluster bool, applicationKey string, authenticati
 
 	presenceUrl = presenceUrl + fmt.Sprintf("presence/%s/%s/%s", applicationKey, authenticationToken, channel)
 	//fmt.Println("presenceUrl: " + presenceUrl)
-	result := asyncHttpGet(presenceUrl)
+	result := httpGet(httpClient, presenceUrl)
 	return result
 }
 
@@ -74,22 +87,35 @@ func getPresence(url string, isCluster bool, applicationKey string, authenticati
 // If success writes the result to the callback channel.
 // An error is returned if there was an error on the request.
 func EnablePresence(url string, isCluster bool, applicationKey string, privateKey string, channel string, metadata bool, callback chan<- PresenceType) {
-	presenceUrl := getServerFromBalancer(url, applicationKey)
-	result := enablePresence(presenceUrl, isCluster, applicationKey, privateKey, channel, metadata, callback)
+	requestEnablePresence(defaultHTTPClient, defaultBalancerCache, url, isCluster, applicationKey, privateKey, channel, metadata, callback)
+}
+
+//EnablePresence works like the EnablePresence function, sending the requests with the http client of c.
+func (c *OrtcClient) EnablePresence(url string, isCluster bool, applicationKey string, privateKey string, channel string, metadata bool, callback chan<- PresenceType) {
+	requestEnablePresence(c.opts.httpClient, c.balancer, url, isCluster, applicationKey, privateKey, channel, metadata, callback)
+}
+
+func requestEnablePresence(httpClient *http.Client, balancer *balancerCache, url string, isCluster bool, applicationKey string, privateKey string, channel string, metadata bool, callback chan<- PresenceType) {
+	presenceUrl, err := serverUrl(httpClient, balancer, url, isCluster, applicationKey)
+	if err != nil {
+		callback <- PresenceType{err, ""}
+		return
+	}
+	result := enablePresence(httpClient, presenceUrl, isCluster, applicationKey, privateKey, channel, metadata, callback)
 	if result.err != nil {
 		callback <- PresenceType{result.err, ""}
 	} else {
 		defer result.response.Body.Close()
 		contents, err := ioutil.ReadAll(result.response.Body)
 		if err != nil {
-			fmt.Printf("%s", err)
+			callback <- PresenceType{err, ""}
 			return
 		}
 		callback <- PresenceType{nil, string(contents[:])}
 	}
 }
 
-func enablePresence(url1 string, isCluster bool, applicationKey string, privateKey string, channel string, metadata bool, callback chan<- PresenceType) *httpResponse {
+func enablePresence(httpClient *http.Client, url1 string, isCluster bool, applicationKey string, privateKey string, channel string, metadata bool, callback chan<- PresenceType) *httpResponse {
 	presenceUrl := ""
 	if len(url1) > 0 {
 		if url1[len(url1)-1] == '/' {
@@ -107,7 +133,7 @@ func enablePresence(url1 string, isCluster bool, applicationKey string, privateK
 		postBody.Add("metadata", "1")
 	}
 
-	result := asyncHttpPost(presenceUrl, postBody)
+	result := httpPost(httpClient, presenceUrl, postBody)
 	return result
 
 }
@@ -116,22 +142,35 @@ func enablePresence(url1 string, isCluster bool, applicationKey string, privateK
 // If success writes the result to the callback channel.
 // An error is returned if there was an error on the request.
 func DisablePresence(url string, isCluster bool, applicationKey string, privateKey string, channel string, callback chan<- PresenceType) {
-	presenceUrl := getServerFromBalancer(url, applicationKey)
-	result := disablePresence(presenceUrl, isCluster, applicationKey, privateKey, channel, callback)
+	requestDisablePresence(defaultHTTPClient, defaultBalancerCache, url, isCluster, applicationKey, privateKey, channel, callback)
+}
+
+//DisablePresence works like the DisablePresence function, sending the requests with the http client of c.
+func (c *OrtcClient) DisablePresence(url string, isCluster bool, applicationKey string, privateKey string, channel string, callback chan<- PresenceType) {
+	requestDisablePresence(c.opts.httpClient, c.balancer, url, isCluster, applicationKey, privateKey, channel, callback)
+}
+
+func requestDisablePresence(httpClient *http.Client, balancer *balancerCache, url string, isCluster bool, applicationKey string, privateKey string, channel string, callback chan<- PresenceType) {
+	presenceUrl, err := serverUrl(httpClient, balancer, url, isCluster, applicationKey)
+	if err != nil {
+		callback <- PresenceType{err, ""}
+		return
+	}
+	result := disablePresence(httpClient, presenceUrl, isCluster, applicationKey, privateKey, channel, callback)
 	if result.err != nil {
 		callback <- PresenceType{result.err, ""}
 	} else {
 		defer result.response.Body.Close()
 		contents, err := ioutil.ReadAll(result.response.Body)
 		if err != nil {
-			fmt.Printf("%s", err)
+			callback <- PresenceType{err, ""}
 			return
 		}
 		callback <- PresenceType{nil, string(contents[:])}
 	}
 }
 
-func disablePresence(url1 string, isCluster bool, applicationKey string, privateKey string, channel string, onDisablePresence chan<- PresenceType) *httpResponse {
+func disablePresence(httpClient *http.Client, url1 string, isCluster bool, applicationKey string, privateKey string, channel string, onDisablePresence chan<- PresenceType) *httpResponse {
 	presenceUrl := ""
 
 	if len(url1) > 0 {
@@ -146,48 +185,20 @@ func disablePresence(url1 string, isCluster bool, applicationKey string, private
 	presenceUrl = presenceUrl + fmt.Sprintf("presence/disable/%s/%s", applicationKey, channel)
 	postBody := url.Values{"privatekey": {privateKey}}
 
-	result := asyncHttpPost(presenceUrl, postBody)
+	result := httpPost(httpClient, presenceUrl, postBody)
 	return result
 }
 
-func asyncHttpGet(url string) *httpResponse {
-	ch := make(chan *httpResponse)
-	var response *httpResponse
-	go func(url string) {
-		//fmt.Printf("Fetching %s \n", url)
-		resp, err := http.Get(url)
-		ch <- &httpResponse{url, resp, err}
-	}(url)
-
-	for {
-		select {
-		case r := <-ch:
-			//fmt.Printf("%s was fetched\n", r.url)
-			response = r
-			return response
-		}
-	}
-	return response
-}
-
-func asyncHttpPost(url string, postBody url.Values) *httpResponse {
-	ch := make(chan *httpResponse)
-	var response *httpResponse
-	go func(url string) {
-		//fmt.Printf("Fetching %s \n", url)
-		resp, err := http.PostForm(url, postBody)
-		ch <- &httpResponse{url, resp, err}
-	}(url)
-
-	for {
-		select {
-		case r := <-ch:
-			//fmt.Printf("%s was fetched\n", r.url)
-			response = r
-			return response
-		}
-	}
-	return response
+//httpGet sends a GET request to url with httpClient.
+func httpGet(httpClient *http.Client, url string) *httpResponse {
+	resp, err := httpClient.Get(url)
+	return &httpResponse{url, resp, err}
+}
+
+//httpPost sends postBody as a form to url with httpClient.
+func httpPost(httpClient *http.Client, url string, postBody url.Values) *httpResponse {
+	resp, err := httpClient.PostForm(url, postBody)
+	return &httpResponse{url, resp, err}
 }
 
 func deserialize(message string) presence {
@@ -0,0 +1,173 @@
+package ortc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+const publish_timeout_default_value = 10 * time.Second
+const publish_retry_interval = 2 * time.Second
+
+//pendingPublish is a published message waiting for the server to acknowledge its parts.
+type pendingPublish struct {
+	channel string
+	total   int
+	//frames holds the commands of the parts not acknowledged yet, by part number.
+	frames map[int]string
+	//seqId is the sequence id the server gave to the last part.
+	seqId string
+}
+
+//unacknowledged returns the numbers of the parts not acknowledged yet, in order.
+func (p *pendingPublish) unacknowledged() []int {
+	parts := make([]int, 0, len(p.frames))
+	for part := range p.frames {
+		parts = append(parts, part)
+	}
+	sort.Ints(parts)
+	return parts
+}
+
+//ackPayload is the content of an ortc-ack frame.
+type ackPayload struct {
+	MessageId string      `json:"m"`
+	Part      json.Number `json:"n"`
+	SeqId     string      `json:"seq"`
+}
+
+//Publish sends a message to the specified channel, stored by the server for ttl so subscribers can
+//replay it, and blocks until the server acknowledges every part of the message.
+//Parts not acknowledged are sent again every 2 seconds, also after a reconnection, until ctx is done.
+//Without a ctx deadline Publish gives up after the publish timeout, 10 seconds by default.
+//It returns the sequence id given by the server to the message, or a *PublishError.
+func (c *OrtcClient) Publish(ctx context.Context, channel, message string, ttl time.Duration) (string, error) {
+	if ttl < time.Second {
+		return "", ortcOutOfRangeException("Publish ttl must be at least one second")
+	}
+
+	c.mu.Lock()
+	permission, err := c.isSendValid(channel, message)
+	if err != nil {
+		c.mu.Unlock()
+		return "", err
+	}
+	messageId := randString(8)
+	pending := &pendingPublish{channel: channel, frames: make(map[int]string)}
+	for i, part := range multiPartMessage(message, messageId, c.opts.maxMessageSize) {
+		pending.frames[i+1] = publishCommand(c.applicationKey, c.authenticationToken, channel, int(ttl/time.Second), permission,
+			part.firtsStr, part.secondStr)
+	}
+	pending.total = len(pending.frames)
+	c.pendingPublishes[messageId] = pending
+	acknowledged := c.publishWaiters.add(messageId)
+	c.mu.Unlock()
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.opts.publishTimeout)
+		defer cancel()
+	}
+
+	ticker := time.NewTicker(publish_retry_interval)
+	defer ticker.Stop()
+	c.sendUnacknowledged(ctx, messageId)
+	for {
+		select {
+		case err := <-acknowledged:
+			return c.publishResult(messageId, pending, err)
+		case <-ticker.C:
+			c.sendUnacknowledged(ctx, messageId)
+		case <-ctx.Done():
+			c.mu.Lock()
+			if _, ok := c.pendingPublishes[messageId]; !ok {
+				//Acknowledged or failed meanwhile, the result is already waiting.
+				c.mu.Unlock()
+				return c.publishResult(messageId, pending, <-acknowledged)
+			}
+			delete(c.pendingPublishes, messageId)
+			c.publishWaiters.remove(messageId, acknowledged)
+			parts := pending.unacknowledged()
+			c.mu.Unlock()
+			return "", &PublishError{channel, messageId, parts, ctx.Err()}
+		}
+	}
+}
+
+func (c *OrtcClient) publishResult(messageId string, pending *pendingPublish, err error) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err != nil {
+		return "", &PublishError{pending.channel, messageId, pending.unacknowledged(), err}
+	}
+	return pending.seqId, nil
+}
+
+//sendUnacknowledged sends the parts of the published message not acknowledged yet. Failures are left to the
+//next retry, since the client may be reconnecting.
+func (c *OrtcClient) sendUnacknowledged(ctx context.Context, messageId string) {
+	c.mu.Lock()
+	pending := c.pendingPublishes[messageId]
+	var frames []string
+	if pending != nil && c.state == StateConnected {
+		for _, part := range pending.unacknowledged() {
+			frames = append(frames, pending.frames[part])
+		}
+	}
+	c.mu.Unlock()
+
+	if len(frames) > 0 {
+		if err := sendMessages(ctx, frames, c, true); err != nil {
+			c.opts.logger.Printf("ortc: publish %s: %v", messageId, err)
+		}
+	}
+}
+
+func publishCommand(applicationKey, authenticationToken, channel string, ttl int, permission, messagePartIdentifier, message string) string {
+	return fmt.Sprintf("publish;%s;%s;%s;%d;%s;%s_%s", applicationKey, authenticationToken, channel, ttl, permission,
+		messagePartIdentifier, escapeCommandField(message))
+}
+
+//onAck records the acknowledgement of a published part and resolves the publish once every part is acknowledged.
+func onAck(c *OrtcClient, message *ortcMessage) {
+	var payload ackPayload
+	content := "{" + strings.Replace(message.message, "\\\"", "\"", -1) + "}"
+	if err := json.Unmarshal([]byte(content), &payload); err != nil {
+		raiseOrtcExceptionEvent(onException, c, ortcInvalidMessageException("Invalid publish acknowledgement: "+message.message))
+		return
+	}
+	part, err := payload.Part.Int64()
+	if err != nil {
+		raiseOrtcExceptionEvent(onException, c, ortcInvalidMessageException("Invalid publish acknowledgement: "+message.message))
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	pending := c.pendingPublishes[payload.MessageId]
+	if pending == nil {
+		return
+	}
+	delete(pending.frames, int(part))
+	if int(part) == pending.total {
+		pending.seqId = payload.SeqId
+	}
+	if len(pending.frames) == 0 {
+		delete(c.pendingPublishes, payload.MessageId)
+		c.publishWaiters.resolve(payload.MessageId, nil)
+	}
+}
+
+//failPublishes resolves the pending publishes to channel, or to every channel if channel is empty, with err.
+//It must be called with c.mu held.
+func (c *OrtcClient) failPublishes(channel string, err error) {
+	for messageId, pending := range c.pendingPublishes {
+		if len(channel) == 0 || pending.channel == channel {
+			delete(c.pendingPublishes, messageId)
+			c.publishWaiters.resolve(messageId, err)
+		}
+	}
+}
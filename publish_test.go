@@ -0,0 +1,146 @@
+package ortc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+var publishedPart = regexp.MustCompile(`^([^_]+)_(\d+)-(\d+)_`)
+
+//publishServer returns a fake server handing the message id and part number of every publish command to onPublish.
+func publishServer(t *testing.T, onPublish func(fc *fakeConn, messageId, part string)) *fakeServer {
+	fs := newFakeServer(t)
+	fs.handle(func(fc *fakeConn, frame string) bool {
+		if !strings.HasPrefix(frame, "publish;") {
+			return false
+		}
+		fields := strings.SplitN(frame, ";", 7)
+		match := publishedPart.FindStringSubmatch(fields[6])
+		onPublish(fc, match[1], match[2])
+		return true
+	})
+	return fs
+}
+
+//ackPart acknowledges a published part, giving it the sequence id seq-<part>.
+func ackPart(fc *fakeConn, messageId, part string) {
+	fc.writeOp("ortc-ack", fmt.Sprintf(`"m":"%s","n":%s,"seq":"seq-%s"`, messageId, part, part))
+}
+
+func TestPublish(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	fs := publishServer(t, ackPart)
+	c := connectClient(t, ctx, fs, WithMaxMessageSize(4))
+
+	seqId, err := c.Publish(ctx, "channel", "hello world", time.Minute)
+	if err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+	if seqId != "seq-3" {
+		t.Fatalf("sequence id = %s, want the one of the last part", seqId)
+	}
+	frames := fs.received("publish;")
+	if len(frames) != 3 {
+		t.Fatalf("%d publish frames sent, want 3", len(frames))
+	}
+	if fields := strings.Split(frames[0], ";"); fields[3] != "channel" || fields[4] != "60" {
+		t.Fatalf("publish frame %s, want channel and a ttl of 60 seconds", frames[0])
+	}
+	c.mu.Lock()
+	pending := len(c.pendingPublishes)
+	c.mu.Unlock()
+	if pending != 0 {
+		t.Fatalf("%d publishes still pending", pending)
+	}
+}
+
+func TestPublishRetry(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	var mu sync.Mutex
+	sent := make(map[string]int)
+	fs := publishServer(t, func(fc *fakeConn, messageId, part string) {
+		mu.Lock()
+		sent[part]++
+		retried := sent[part] > 1
+		mu.Unlock()
+		//The second part is lost the first time.
+		if part != "2" || retried {
+			ackPart(fc, messageId, part)
+		}
+	})
+	c := connectClient(t, ctx, fs, WithMaxMessageSize(4))
+
+	seqId, err := c.Publish(ctx, "channel", "hello world", time.Minute)
+	if err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+	if seqId != "seq-3" {
+		t.Fatalf("sequence id = %s, want the one of the last part", seqId)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if sent["1"] != 1 || sent["2"] != 2 || sent["3"] != 1 {
+		t.Fatalf("parts sent %v, want only the second one sent again", sent)
+	}
+}
+
+func TestPublishTimeout(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	fs := publishServer(t, func(fc *fakeConn, messageId, part string) {
+		if part != "2" {
+			ackPart(fc, messageId, part)
+		}
+	})
+	c := connectClient(t, ctx, fs, WithMaxMessageSize(4))
+
+	publishCtx, publishCancel := context.WithTimeout(ctx, 300*time.Millisecond)
+	defer publishCancel()
+	_, err := c.Publish(publishCtx, "channel", "hello world", time.Minute)
+	var publishError *PublishError
+	if !errors.As(err, &publishError) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("publish = %v, want a *PublishError for the deadline", err)
+	}
+	if publishError.Channel != "channel" || len(publishError.Parts) != 1 || publishError.Parts[0] != 2 {
+		t.Fatalf("publish error = %+v, want part 2 not acknowledged", publishError)
+	}
+	c.mu.Lock()
+	pending := len(c.pendingPublishes)
+	c.mu.Unlock()
+	if pending != 0 {
+		t.Fatalf("%d publishes still pending", pending)
+	}
+}
+
+func TestPublishServerError(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	fs := publishServer(t, func(fc *fakeConn, messageId, part string) {
+		fc.writeOp("ortc-error", `"ex":{"op":"publish","ch":"channel","ex":"Publish not allowed"}`)
+	})
+	c := connectClient(t, ctx, fs)
+
+	_, err := c.Publish(ctx, "channel", "hello", time.Minute)
+	var publishError *PublishError
+	var serverError *ServerError
+	if !errors.As(err, &publishError) || !errors.As(err, &serverError) {
+		t.Fatalf("publish = %v, want a *PublishError wrapping a *ServerError", err)
+	}
+	if serverError.Operation != ErrorOpPublish || serverError.Channel != "channel" || serverError.Message != "Publish not allowed" {
+		t.Fatalf("server error = %+v", serverError)
+	}
+	if len(publishError.Parts) != 1 || publishError.Parts[0] != 1 {
+		t.Fatalf("publish error = %+v, want part 1 not acknowledged", publishError)
+	}
+	if state := c.State(); state != StateConnected {
+		t.Fatalf("state = %v, want %v", state, StateConnected)
+	}
+}
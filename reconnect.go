@@ -0,0 +1,94 @@
+package ortc
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+)
+
+//ReconnectPolicy decides when the client tries to reconnect after losing its connection.
+type ReconnectPolicy interface {
+	//NextDelay returns how long to wait before attempt, counted from 1, given the time elapsed
+	//since the connection was lost. Returning false stops reconnecting.
+	NextDelay(attempt int, elapsed time.Duration) (time.Duration, bool)
+}
+
+//ExponentialBackoff is a ReconnectPolicy waiting a random delay between zero and an exponentially
+//growing ceiling (full jitter), so clients disconnected together do not reconnect in lockstep.
+type ExponentialBackoff struct {
+	//Initial is the ceiling of the first delay.
+	Initial time.Duration
+	//Max bounds the ceiling of every delay.
+	Max time.Duration
+	//Multiplier is the growth factor of the ceiling between attempts. Values below 1 mean 2.
+	Multiplier float64
+	//MaxAttempts stops reconnecting after that many attempts. Zero means no limit.
+	MaxAttempts int
+	//MaxElapsed stops reconnecting once the connection has been lost for that long. Zero means no limit.
+	MaxElapsed time.Duration
+}
+
+func (b *ExponentialBackoff) NextDelay(attempt int, elapsed time.Duration) (time.Duration, bool) {
+	if b.MaxAttempts > 0 && attempt > b.MaxAttempts {
+		return 0, false
+	}
+	if b.MaxElapsed > 0 && elapsed >= b.MaxElapsed {
+		return 0, false
+	}
+
+	multiplier := b.Multiplier
+	if multiplier < 1 {
+		multiplier = 2
+	}
+	ceiling := float64(b.Initial) * math.Pow(multiplier, float64(attempt-1))
+	if b.Max > 0 && ceiling > float64(b.Max) {
+		ceiling = float64(b.Max)
+	}
+	if ceiling > math.MaxInt64-1 {
+		ceiling = math.MaxInt64 - 1
+	}
+
+	delay := time.Duration(0)
+	if ceiling >= 1 {
+		delay = time.Duration(rand.Int63n(int64(ceiling) + 1))
+	}
+	if b.MaxElapsed > 0 && elapsed+delay > b.MaxElapsed {
+		delay = b.MaxElapsed - elapsed
+	}
+	return delay, true
+}
+
+//defaultReconnectPolicy backs off up to maxDelay, starting from one second at most.
+func defaultReconnectPolicy(maxDelay time.Duration) ReconnectPolicy {
+	initial := time.Second
+	if maxDelay < initial {
+		initial = maxDelay
+	}
+	return &ExponentialBackoff{Initial: initial, Max: maxDelay, Multiplier: 2}
+}
+
+//ReconnectAttempt describes a scheduled reconnection attempt.
+type ReconnectAttempt struct {
+	//Attempt is the number of the attempt since the connection was lost, counted from 1.
+	Attempt int
+	//Delay is how long the client waits before the attempt.
+	Delay time.Duration
+	//Elapsed is the time since the connection was lost.
+	Elapsed time.Duration
+}
+
+//ReconnectError is raised as an exception when the ReconnectPolicy gives up.
+//It wraps ErrReconnectFailed.
+type ReconnectError struct {
+	Attempts int
+	Elapsed  time.Duration
+}
+
+func (e *ReconnectError) Error() string {
+	return fmt.Sprintf("Gave up reconnecting after %d attempts in %v", e.Attempts, e.Elapsed)
+}
+
+func (e *ReconnectError) Unwrap() error {
+	return ErrReconnectFailed
+}
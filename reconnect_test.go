@@ -0,0 +1,54 @@
+package ortc
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+//fixedDelay is a ReconnectPolicy waiting the same delay before every attempt.
+type fixedDelay time.Duration
+
+func (d fixedDelay) NextDelay(attempt int, elapsed time.Duration) (time.Duration, bool) {
+	return time.Duration(d), true
+}
+
+func TestReconnectAttemptEvents(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	fs := newFakeServer(t)
+	c := NewClient(WithEventBufferSize(16), WithReconnectPolicy(fixedDelay(20*time.Millisecond)))
+	events := c.Events()
+	if err := c.ConnectContext(ctx, "appKey", "token", "", fs.URL, false, false); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer c.Disconnect()
+
+	fs.dropConnections()
+	select {
+	case <-events.OnReconnected:
+	case <-ctx.Done():
+		t.Fatal("client did not reconnect")
+	}
+	if attempt := c.LastReconnectAttempt(); attempt.Attempt != 0 {
+		t.Fatalf("LastReconnectAttempt after reconnecting = %+v", attempt)
+	}
+
+	//The attempt is still available to a consumer reading after the reconnection.
+	select {
+	case attempt := <-events.OnReconnectAttempt:
+		if attempt.Attempt != 1 || attempt.Delay != 20*time.Millisecond {
+			t.Fatalf("attempt = %+v, want the first attempt after 20ms", attempt)
+		}
+	default:
+		t.Fatal("no attempt delivered on OnReconnectAttempt")
+	}
+	select {
+	case sender := <-events.OnReconnecting:
+		if sender != c {
+			t.Fatalf("reconnecting sender = %p, want %p", sender, c)
+		}
+	default:
+		t.Fatal("no OnReconnecting event")
+	}
+}
@@ -1,8 +1,10 @@
 package ortc
 
 import (
+	"context"
 	"fmt"
 	"github.com/realtime-framework/RealtimeMessaging-Go/authentication"
+	"net/http"
 	"net/url"
 )
 
@@ -11,29 +13,58 @@ import (
 func SaveAuthentication(url1 string, isCluster bool, authenticationToken string, authenticationTokenIsPrivate bool, applicationKey string,
 	timeToLive int, privateKey string, permissions map[string][]authentication.ChannelPermissions) bool {
 
-	connectionUrl := url1
+	isAuthenticated, _ := SaveAuthenticationContext(context.Background(), url1, isCluster, authenticationToken, authenticationTokenIsPrivate,
+		applicationKey, timeToLive, privateKey, permissions)
+	return isAuthenticated
+}
 
-	if isCluster {
-		connectionUrl = getServerFromBalancer(url1, applicationKey)
-	}
+//SaveAuthenticationContext works like SaveAuthentication and returns why the permissions could not be saved:
+//a *BalancerError if the cluster balancer gave no server, or an error matching ErrNotAuthorized if the request failed.
+//If the server answers but does not accept the permissions it returns false and a nil error.
+func SaveAuthenticationContext(ctx context.Context, url1 string, isCluster bool, authenticationToken string, authenticationTokenIsPrivate bool,
+	applicationKey string, timeToLive int, privateKey string, permissions map[string][]authentication.ChannelPermissions) (bool, error) {
+
+	return saveAuthentication(ctx, defaultHTTPClient, defaultBalancerCache, url1, isCluster, authenticationToken, authenticationTokenIsPrivate,
+		applicationKey, timeToLive, privateKey, permissions)
+}
 
-	isAuthenticated := false
+//SaveAuthentication works like the SaveAuthentication function, sending the requests with the http client of c.
+func (c *OrtcClient) SaveAuthentication(url1 string, isCluster bool, authenticationToken string, authenticationTokenIsPrivate bool, applicationKey string,
+	timeToLive int, privateKey string, permissions map[string][]authentication.ChannelPermissions) bool {
+
+	isAuthenticated, _ := c.SaveAuthenticationContext(context.Background(), url1, isCluster, authenticationToken, authenticationTokenIsPrivate,
+		applicationKey, timeToLive, privateKey, permissions)
+	return isAuthenticated
+}
 
-	u, err := url.Parse(fmt.Sprintf("%s/authenticate", connectionUrl))
+//SaveAuthenticationContext works like the SaveAuthenticationContext function, sending the requests with the http client of c.
+func (c *OrtcClient) SaveAuthenticationContext(ctx context.Context, url1 string, isCluster bool, authenticationToken string,
+	authenticationTokenIsPrivate bool, applicationKey string, timeToLive int, privateKey string,
+	permissions map[string][]authentication.ChannelPermissions) (bool, error) {
+
+	return saveAuthentication(ctx, c.opts.httpClient, c.balancer, url1, isCluster, authenticationToken, authenticationTokenIsPrivate,
+		applicationKey, timeToLive, privateKey, permissions)
+}
+
+func saveAuthentication(ctx context.Context, httpClient *http.Client, balancer *balancerCache, url1 string, isCluster bool, authenticationToken string,
+	authenticationTokenIsPrivate bool, applicationKey string, timeToLive int, privateKey string,
+	permissions map[string][]authentication.ChannelPermissions) (bool, error) {
+
+	connectionUrl, err := serverUrl(httpClient, balancer, url1, isCluster, applicationKey)
 	if err != nil {
-		fmt.Println("Error /authenticate")
-		ortcAuthenticationNotAuthorizedException(err.Error())
+		return false, err
 	}
 
-	authenticationUrl := u
-	//fmt.Println("AuthenticationUrl : " + authenticationUrl.String())
+	authenticationUrl, err := url.Parse(fmt.Sprintf("%s/authenticate", connectionUrl))
+	if err != nil {
+		return false, ortcInvalidCharactersException("URL")
+	}
 
-	isAuthenticated, err = authentication.SaveAuthentication(authenticationUrl, authenticationToken,
+	isAuthenticated, err := authentication.SaveAuthenticationWithContext(ctx, httpClient, authenticationUrl, authenticationToken,
 		authenticationTokenIsPrivate, applicationKey, timeToLive, privateKey, permissions)
-
 	if err != nil {
-		ortcAuthenticationNotAuthorizedException(err.Error())
+		return false, ortcAuthenticationNotAuthorizedException(err.Error())
 	}
 
-	return isAuthenticated
+	return isAuthenticated, nil
 }
@@ -0,0 +1,47 @@
+package ortc
+
+import (
+	"context"
+	"errors"
+	"github.com/realtime-framework/RealtimeMessaging-Go/authentication"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSaveAuthenticationContext(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	status := http.StatusCreated
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/authenticate":
+			w.WriteHeader(status)
+		default:
+			http.Error(w, "balancer down", http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+	c := NewClient(WithEventHandler(HandlerFuncs{}))
+	permissions := map[string][]authentication.ChannelPermissions{"channel": {authentication.Read}}
+
+	if ok, err := c.SaveAuthenticationContext(ctx, server.URL, false, "token", false, "appKey", 60, "privateKey", permissions); !ok || err != nil {
+		t.Fatalf("accepted authentication = %v, %v", ok, err)
+	}
+
+	status = http.StatusUnauthorized
+	if ok, err := c.SaveAuthenticationContext(ctx, server.URL, false, "token", false, "appKey", 60, "privateKey", permissions); ok || err != nil {
+		t.Fatalf("rejected authentication = %v, %v", ok, err)
+	}
+
+	if ok, err := c.SaveAuthenticationContext(ctx, "http://%zz", false, "token", false, "appKey", 60, "privateKey", permissions); ok || err == nil {
+		t.Fatalf("invalid url = %v, %v", ok, err)
+	}
+
+	ok, err := c.SaveAuthenticationContext(ctx, server.URL+"/balancer", true, "token", false, "appKey", 60, "privateKey", permissions)
+	var balancerError *BalancerError
+	if ok || !errors.As(err, &balancerError) || !errors.Is(err, ErrBalancer) {
+		t.Fatalf("balancer failure = %v, %v, want a *BalancerError", ok, err)
+	}
+}
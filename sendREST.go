@@ -0,0 +1,86 @@
+package ortc
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+//SendREST sends a message to the specified channel with an http request authenticated by the private key,
+//without connecting a client. Messages larger than the maximum message size are sent in parts, like Send does.
+//It returns nil if every part was accepted, otherwise a *RESTSendError listing the failed parts.
+func SendREST(ctx context.Context, url string, isCluster bool, applicationKey, privateKey, channel, message string) error {
+	return sendREST(ctx, defaultHTTPClient, defaultBalancerCache, max_message_size, url, isCluster, applicationKey, privateKey,
+		channel, message)
+}
+
+//SendREST works like the SendREST function, sending the requests with the http client of c.
+func (c *OrtcClient) SendREST(ctx context.Context, url string, isCluster bool, applicationKey, privateKey, channel, message string) error {
+	return sendREST(ctx, c.opts.httpClient, c.balancer, c.opts.maxMessageSize, url, isCluster, applicationKey, privateKey,
+		channel, message)
+}
+
+func sendREST(ctx context.Context, httpClient *http.Client, balancer *balancerCache, maxMessageSize int, url1 string, isCluster bool,
+	applicationKey, privateKey, channel, message string) error {
+
+	if len(url1) == 0 {
+		return ortcEmptyFieldException("URL")
+	} else if len(applicationKey) == 0 {
+		return ortcEmptyFieldException("Application key")
+	} else if len(privateKey) == 0 {
+		return ortcEmptyFieldException("Private key")
+	} else if err := isMessageValid(channel, message); err != nil {
+		return err
+	}
+
+	server, err := serverUrl(httpClient, balancer, url1, isCluster, applicationKey)
+	if err != nil {
+		return err
+	}
+	sendUrl := strings.TrimSuffix(server, "/") + "/send"
+
+	messageId := randString(8)
+	parts := multiPartMessage(message, messageId, maxMessageSize)
+	var failed []*PartError
+	for i, part := range parts {
+		postBody := url.Values{
+			"AK": {applicationKey},
+			"PK": {privateKey},
+			"C":  {channel},
+			"M":  {fmt.Sprintf("%s_%s", part.firtsStr, part.secondStr)},
+		}
+		if statusCode, err := postSend(ctx, httpClient, sendUrl, postBody); err != nil {
+			failed = append(failed, &PartError{i + 1, len(parts), statusCode, err})
+		}
+	}
+
+	if len(failed) > 0 {
+		return &RESTSendError{channel, messageId, failed}
+	}
+	return nil
+}
+
+//postSend posts a message part to the send endpoint, failing unless the server answers with a 2xx status.
+//It returns the status code of the response, zero if there was none.
+func postSend(ctx context.Context, httpClient *http.Client, sendUrl string, postBody url.Values) (int, error) {
+	request, err := http.NewRequest("POST", sendUrl, strings.NewReader(postBody.Encode()))
+	if err != nil {
+		return 0, err
+	}
+	request = request.WithContext(ctx)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+	body, _ := ioutil.ReadAll(response.Body)
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return response.StatusCode, fmt.Errorf("server returned %s: %s", response.Status, strings.TrimSpace(string(body)))
+	}
+	return response.StatusCode, nil
+}
@@ -0,0 +1,106 @@
+package ortc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+//restServer records the forms posted to its send endpoint, failing the parts for which fail returns true.
+func restServer(t *testing.T, fail func(form url.Values) bool) (*httptest.Server, func() []url.Values) {
+	var mu sync.Mutex
+	var forms []url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/send" {
+			http.NotFound(w, r)
+			return
+		}
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		forms = append(forms, r.PostForm)
+		mu.Unlock()
+		if fail(r.PostForm) {
+			http.Error(w, "part rejected", http.StatusInternalServerError)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, func() []url.Values {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]url.Values(nil), forms...)
+	}
+}
+
+var restPart = regexp.MustCompile(`^([^_]+)_(\d)-3_(.*)$`)
+
+func TestSendREST(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	server, posted := restServer(t, func(form url.Values) bool { return false })
+	fb := newFakeBalancer(t, server.URL)
+
+	err := sendREST(ctx, http.DefaultClient, newBalancerCache(time.Minute), 4, fb.URL, true, "appKey", "privateKey", "channel",
+		"hello world")
+	if err != nil {
+		t.Fatalf("SendREST: %v", err)
+	}
+	forms := posted()
+	if len(forms) != 3 {
+		t.Fatalf("%d parts posted, want 3", len(forms))
+	}
+	var messageId, message string
+	for i, form := range forms {
+		if form.Get("AK") != "appKey" || form.Get("PK") != "privateKey" || form.Get("C") != "channel" {
+			t.Fatalf("part %d posted %v", i+1, form)
+		}
+		match := restPart.FindStringSubmatch(form.Get("M"))
+		if match == nil || match[2] != strconv.Itoa(i+1) || (messageId != "" && match[1] != messageId) {
+			t.Fatalf("part %d posted the message %q", i+1, form.Get("M"))
+		}
+		messageId = match[1]
+		message += match[3]
+	}
+	if message != "hello world" {
+		t.Fatalf("parts posted %q", message)
+	}
+	if n := fb.requestCount(); n != 1 {
+		t.Fatalf("balancer asked %d times, want 1", n)
+	}
+}
+
+func TestSendRESTPartFailed(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	server, posted := restServer(t, func(form url.Values) bool {
+		match := restPart.FindStringSubmatch(form.Get("M"))
+		return match != nil && match[2] == "2"
+	})
+
+	err := sendREST(ctx, http.DefaultClient, newBalancerCache(time.Minute), 4, server.URL+"/", false, "appKey", "privateKey",
+		"channel", "hello world")
+	var sendError *RESTSendError
+	if !errors.As(err, &sendError) {
+		t.Fatalf("SendREST = %v, want a *RESTSendError", err)
+	}
+	if sendError.Channel != "channel" || len(sendError.Parts) != 1 {
+		t.Fatalf("send error = %+v, want one failed part", sendError)
+	}
+	var partError *PartError
+	if !errors.As(err, &partError) || partError.Part != 2 || partError.Total != 3 || partError.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("part error = %+v, want part 2 of 3 rejected with status 500", partError)
+	}
+	//The other parts are still sent.
+	if n := len(posted()); n != 3 {
+		t.Fatalf("%d parts posted, want 3", n)
+	}
+}
@@ -0,0 +1,121 @@
+package ortc
+
+import "strconv"
+
+//State is a stage of the lifecycle of an OrtcClient.
+type State int
+
+const (
+	//StateIdle is the state of a client that never connected.
+	StateIdle State = iota
+	//StateResolving retrieves the server url from the cluster balancer.
+	StateResolving
+	//StateDialing opens the websocket connection.
+	StateDialing
+	//StateValidating waits for the server to validate the connection.
+	StateValidating
+	//StateConnected is the state of a validated connection.
+	StateConnected
+	//StateReconnecting waits before trying to connect again after the connection was lost.
+	StateReconnecting
+	//StateClosing closes the connection after Disconnect or a fatal error.
+	StateClosing
+	//StateClosed is the state of a disconnected client. It may connect again.
+	StateClosed
+)
+
+var stateNames = map[State]string{
+	StateIdle:         "Idle",
+	StateResolving:    "Resolving",
+	StateDialing:      "Dialing",
+	StateValidating:   "Validating",
+	StateConnected:    "Connected",
+	StateReconnecting: "Reconnecting",
+	StateClosing:      "Closing",
+	StateClosed:       "Closed",
+}
+
+func (s State) String() string {
+	if name, ok := stateNames[s]; ok {
+		return name
+	}
+	return "State(" + strconv.Itoa(int(s)) + ")"
+}
+
+//stateTransitions lists the states each state may move to.
+var stateTransitions = map[State][]State{
+	StateIdle:         {StateResolving, StateDialing},
+	StateResolving:    {StateDialing, StateReconnecting, StateClosing, StateClosed},
+	StateDialing:      {StateValidating, StateReconnecting, StateClosing, StateClosed},
+	StateValidating:   {StateConnected, StateReconnecting, StateClosing, StateClosed},
+	StateConnected:    {StateReconnecting, StateClosing},
+	StateReconnecting: {StateResolving, StateDialing, StateClosing},
+	StateClosing:      {StateClosed},
+	StateClosed:       {StateResolving, StateDialing},
+}
+
+func canTransition(from, to State) bool {
+	for _, state := range stateTransitions[from] {
+		if state == to {
+			return true
+		}
+	}
+	return false
+}
+
+//StateChange is a transition of the client state, delivered on the StateChanges channel.
+//Cause is the error that triggered it, if any.
+type StateChange struct {
+	Sender   *OrtcClient
+	Previous State
+	Next     State
+	Cause    error
+}
+
+//State returns the current state of the client.
+func (c *OrtcClient) State() State {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.state
+}
+
+//StateChanges returns the channel receiving every transition of the client state.
+//It never blocks the client: when its buffer is full the oldest change is dropped.
+func (c *OrtcClient) StateChanges() <-chan StateChange {
+	return c.stateChanges
+}
+
+//setState moves the client to next, rejecting illegal transitions. It must be called with c.mu held.
+func (c *OrtcClient) setState(next State, cause error) error {
+	if !canTransition(c.state, next) {
+		return &StateError{c.state, next}
+	}
+	change := StateChange{c, c.state, next, cause}
+	c.state = next
+
+	ch := c.stateChanges
+	deliver(c, OverflowDropOldest, StreamState, func(block bool) bool {
+		select {
+		case ch <- change:
+			return true
+		default:
+			return false
+		}
+	}, func() bool {
+		select {
+		case <-ch:
+			return true
+		default:
+			return false
+		}
+	})
+	return nil
+}
+
+//dialState is the first state of a connection attempt. It must be called with c.mu held.
+func (c *OrtcClient) dialState() State {
+	if c.isCluster {
+		return StateResolving
+	}
+	return StateDialing
+}
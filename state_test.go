@@ -0,0 +1,69 @@
+package ortc
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestStateChanges(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	fs := newFakeServer(t)
+	reconnected := make(chan struct{}, 1)
+	c := connectClient(t, ctx, fs, WithReconnectPolicy(fixedDelay(20*time.Millisecond)), WithEventHandler(HandlerFuncs{
+		Reconnected: func(c *OrtcClient) { reconnected <- struct{}{} },
+	}))
+
+	fs.dropConnections()
+	select {
+	case <-reconnected:
+	case <-ctx.Done():
+		t.Fatal("client did not reconnect")
+	}
+	c.Disconnect()
+
+	want := []State{StateIdle, StateDialing, StateValidating, StateConnected, StateReconnecting,
+		StateDialing, StateValidating, StateConnected, StateClosing, StateClosed}
+	for i := 1; i < len(want); i++ {
+		var change StateChange
+		select {
+		case change = <-c.StateChanges():
+		default:
+			t.Fatalf("change %d missing, want %v to %v", i, want[i-1], want[i])
+		}
+		if change.Sender != c || change.Previous != want[i-1] || change.Next != want[i] {
+			t.Fatalf("change %d = %v to %v, want %v to %v", i, change.Previous, change.Next, want[i-1], want[i])
+		}
+		//Only losing the connection has a cause, the read error.
+		if (change.Next == StateReconnecting) != (change.Cause != nil) {
+			t.Fatalf("change %d to %v has cause %v", i, change.Next, change.Cause)
+		}
+	}
+	if len(c.StateChanges()) != 0 {
+		t.Fatalf("%d unexpected state changes", len(c.StateChanges()))
+	}
+}
+
+func TestIllegalStateTransition(t *testing.T) {
+	c := NewClient(WithEventHandler(HandlerFuncs{}))
+	c.mu.Lock()
+	err := c.setState(StateConnected, nil)
+	state := c.state
+	c.mu.Unlock()
+
+	var stateError *StateError
+	if !errors.As(err, &stateError) || !errors.Is(err, ErrInvalidState) || stateError.From != StateIdle || stateError.To != StateConnected {
+		t.Fatalf("setState = %v, want a *StateError from Idle to Connected", err)
+	}
+	if state != StateIdle || len(c.StateChanges()) != 0 {
+		t.Fatalf("rejected transition changed the state to %v", state)
+	}
+	if c.shutdown(nil, true) {
+		t.Fatal("a client that never connected was shut down")
+	}
+	if err := c.UnsubscribeContext(context.Background(), "channel"); !errors.Is(err, ErrNotConnected) {
+		t.Fatalf("unsubscribe = %v, want ErrNotConnected", err)
+	}
+}
@@ -1,9 +1,11 @@
 package ortc
 
 import (
+	"fmt"
 	"math/rand"
 	"net/url"
 	"regexp"
+	"strings"
 	"time"
 )
 
@@ -22,11 +24,79 @@ func ortcIsValidUrl(uri string) bool {
 	return true
 }
 
+//socketUrl builds the websocket url of a connection to server, keeping its host, port, base path and query,
+//for instance parameters expected by a proxy. http and ws servers are dialed with ws, https and wss servers with wss.
+func socketUrl(server string, serverId int, sessionId string) (*url.URL, error) {
+	u, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	scheme := ""
+	switch strings.ToLower(u.Scheme) {
+	case "http", unsecure:
+		scheme = unsecure
+	case "https", secure:
+		scheme = secure
+	default:
+		return nil, fmt.Errorf("unsupported url scheme %q", u.Scheme)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("url %q has no host", server)
+	}
+
+	return &url.URL{
+		Scheme:   scheme,
+		Host:     u.Host,
+		Path:     fmt.Sprintf("%s/broadcast/%d/%s/websocket", strings.TrimSuffix(u.Path, "/"), serverId, sessionId),
+		RawQuery: u.RawQuery,
+	}, nil
+}
+
+//ortcIsValidServerUrl reports whether the client can connect to server, an http, https, ws or wss url.
+func ortcIsValidServerUrl(server string) bool {
+	_, err := socketUrl(server, 0, "")
+	return err == nil
+}
+
 func ortcIsValidInput(input string) bool {
 	match, _ := regexp.MatchString(`[\w-:\/.]*$`, input)
 	return match
 }
 
+//ortcIsValidFilter checks the syntax of a subscription filter: it must not be empty nor contain the ';'
+//command separator, and its quotes and parentheses must be balanced.
+func ortcIsValidFilter(filter string) error {
+	if len(strings.TrimSpace(filter)) == 0 {
+		return ortcEmptyFieldException("Filter")
+	}
+	depth := 0
+	quoted := false
+	for _, r := range filter {
+		switch {
+		case r == ';' || r == '\n' || r == '\r':
+			return ortcInvalidFilterException(fmt.Sprintf("Filter has invalid character %q", r))
+		case r == '\'':
+			quoted = !quoted
+		case quoted:
+		case r == '(':
+			depth++
+		case r == ')':
+			depth--
+			if depth < 0 {
+				return ortcInvalidFilterException("Filter has unbalanced parentheses")
+			}
+		}
+	}
+	if quoted {
+		return ortcInvalidFilterException("Filter has an unterminated string")
+	}
+	if depth != 0 {
+		return ortcInvalidFilterException("Filter has unbalanced parentheses")
+	}
+	return nil
+}
+
 func randString(n int) string {
 	rand.Seed(time.Now().UTC().UnixNano())
 	b := make([]rune, n)
@@ -0,0 +1,45 @@
+package ortc
+
+import "testing"
+
+func TestSocketUrl(t *testing.T) {
+	tests := []struct {
+		server string
+		valid  bool
+		scheme string
+		host   string
+		path   string
+		query  string
+	}{
+		{"http://h:8080/server/2.1", true, "ws", "h:8080", "/server/2.1/broadcast/7/session/websocket", ""},
+		{"https://[::1]:443/p/", true, "wss", "[::1]:443", "/p/broadcast/7/session/websocket", ""},
+		{"ws://h", true, "ws", "h", "/broadcast/7/session/websocket", ""},
+		{"wss://h", true, "wss", "h", "/broadcast/7/session/websocket", ""},
+		{"HTTPS://h", true, "wss", "h", "/broadcast/7/session/websocket", ""},
+		{"http://h/", true, "ws", "h", "/broadcast/7/session/websocket", ""},
+		{"http://h/server/2.1?token=abc&region=eu", true, "ws", "h", "/server/2.1/broadcast/7/session/websocket", "token=abc&region=eu"},
+		{"ftp://h", false, "", "", "", ""},
+		{"h:80", false, "", "", "", ""},
+		{"/server/2.1", false, "", "", "", ""},
+		{"http://%zz", false, "", "", "", ""},
+	}
+	for _, tt := range tests {
+		if valid := ortcIsValidServerUrl(tt.server); valid != tt.valid {
+			t.Errorf("ortcIsValidServerUrl(%q) = %v, want %v", tt.server, valid, tt.valid)
+		}
+		u, err := socketUrl(tt.server, 7, "session")
+		if !tt.valid {
+			if err == nil {
+				t.Errorf("socketUrl(%q) = %v, want an error", tt.server, u)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("socketUrl(%q): %v", tt.server, err)
+			continue
+		}
+		if u.Scheme != tt.scheme || u.Host != tt.host || u.Path != tt.path || u.RawQuery != tt.query {
+			t.Errorf("socketUrl(%q) = %v, want scheme %s, host %s, path %s and query %q", tt.server, u, tt.scheme, tt.host, tt.path, tt.query)
+		}
+	}
+}
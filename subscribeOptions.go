@@ -0,0 +1,76 @@
+package ortc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+//SubscribeOptions are the settings of a subscription made with SubscribeWithOptions.
+type SubscribeOptions struct {
+	//SubscribeOnReconnect subscribes the channel again when the client reconnects.
+	SubscribeOnReconnect bool
+	//Filter delivers only the messages matching the expression, see SubscribeWithFilter.
+	Filter string
+	//SeqId asks the server to replay the messages it buffered after the message with this sequence id.
+	SeqId string
+	//RegistrationId and Platform forward the messages of the channel to a mobile device as push
+	//notifications, see SubscribeWithNotifications.
+	RegistrationId string
+	Platform       Platform
+}
+
+//subscribeOptionsPayload is the last field of the subscribeoptions command.
+type subscribeOptionsPayload struct {
+	Filter         string `json:"filter,omitempty"`
+	SeqId          string `json:"seqId,omitempty"`
+	RegistrationId string `json:"regId,omitempty"`
+	Platform       string `json:"platform,omitempty"`
+}
+
+//SubscribeWithOptions subscribes the specified channel with options and blocks until the server confirms
+//the subscription. The client tracks the sequence id of the last message received on the channel, see
+//LastSeqId, and asks the server to replay the messages missed while reconnecting.
+func (c *OrtcClient) SubscribeWithOptions(ctx context.Context, channel string, options SubscribeOptions) (<-chan onMessageChannel, error) {
+	if len(options.Filter) > 0 {
+		if err := ortcIsValidFilter(options.Filter); err != nil {
+			return nil, err
+		}
+	}
+	if len(options.RegistrationId) > 0 || len(options.Platform) > 0 {
+		if err := ortcIsValidRegistration(options.RegistrationId, options.Platform); err != nil {
+			return nil, err
+		}
+	}
+	return c.subscribeContext(ctx, channel, options)
+}
+
+//LastSeqId returns the sequence id of the last message received on the specified channel, to be stored
+//as a checkpoint and given back in SubscribeOptions. It is empty if no message carried a sequence id,
+//and is forgotten once the channel is unsubscribed.
+func (c *OrtcClient) LastSeqId(channel string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.seqIds[channel]
+}
+
+//subscribeCommand returns the command subscribing channel with the filter and push registration of subscription
+//and the replay sequence id, if any. Options are combined in a subscribeoptions command.
+func subscribeCommand(applicationKey, authenticationToken, channel, permission string, subscription *channelSubscription, seqId string) string {
+	filter := subscription.filter
+	registrationId := subscription.registrationId
+	if len(seqId) > 0 || (len(filter) > 0 && len(registrationId) > 0) {
+		payload, _ := json.Marshal(subscribeOptionsPayload{filter, seqId, registrationId, string(subscription.platform)})
+		return fmt.Sprintf("subscribeoptions;%s;%s;%s;%s;%s", applicationKey, authenticationToken, channel, permission,
+			escapeCommandField(string(payload)))
+	}
+	if len(filter) > 0 {
+		return fmt.Sprintf("subscribefilter;%s;%s;%s;%s;%s", applicationKey, authenticationToken, channel, permission,
+			escapeCommandField(filter))
+	}
+	if len(registrationId) > 0 {
+		return fmt.Sprintf("subscribe;%s;%s;%s;%s;%s;%s", applicationKey, authenticationToken, channel, permission,
+			registrationId, subscription.platform)
+	}
+	return fmt.Sprintf("subscribe;%s;%s;%s;%s", applicationKey, authenticationToken, channel, permission)
+}
@@ -0,0 +1,60 @@
+package ortc
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSeqIdForgottenOnUnsubscribe(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	fs := newFakeServer(t)
+	var subscriptions int32
+	fs.handle(func(fc *fakeConn, frame string) bool {
+		if !strings.HasPrefix(frame, "subscribe;") || atomic.AddInt32(&subscriptions, 1) > 1 {
+			return false
+		}
+		fc.writeOp("ortc-subscribed", `"ch":"channel"`)
+		fc.write(`a["{\"ch\":\"channel\",\"s\":\"seq-1\",\"m\":\"abcdefgh_1-1_hello\"}"]`)
+		return true
+	})
+	reconnected := make(chan struct{}, 1)
+	c := connectClient(t, ctx, fs, WithReconnectPolicy(fixedDelay(20*time.Millisecond)), WithEventHandler(HandlerFuncs{
+		Reconnected: func(c *OrtcClient) { reconnected <- struct{}{} },
+	}))
+
+	onMessage, err := c.SubscribeContext(ctx, "channel", true)
+	if err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+	if msg := <-onMessage; msg.SeqId != "seq-1" {
+		t.Fatalf("message = %+v, want sequence id seq-1", msg)
+	}
+	if seqId := c.LastSeqId("channel"); seqId != "seq-1" {
+		t.Fatalf("LastSeqId = %q, want seq-1", seqId)
+	}
+
+	if err := c.UnsubscribeContext(ctx, "channel"); err != nil {
+		t.Fatalf("unsubscribe: %v", err)
+	}
+	if seqId := c.LastSeqId("channel"); seqId != "" {
+		t.Fatalf("LastSeqId after unsubscribing = %q, want none", seqId)
+	}
+
+	if _, err := c.SubscribeContext(ctx, "channel", true); err != nil {
+		t.Fatalf("subscribe again: %v", err)
+	}
+	fs.dropConnections()
+	select {
+	case <-reconnected:
+	case <-ctx.Done():
+		t.Fatal("client did not reconnect")
+	}
+	waitFor(t, "the channel to be subscribed again", func() bool { return len(fs.received("subscribe")) == 3 })
+	if frames := fs.received("subscribeoptions;"); len(frames) > 0 {
+		t.Fatalf("resubscribed replaying a stale sequence id: %v", frames)
+	}
+}
@@ -0,0 +1,40 @@
+package ortc
+
+//waiters holds the callers blocked until the server confirms an operation, keyed by channel name.
+//It must be accessed with the client mutex held.
+type waiters map[string][]chan error
+
+func (w waiters) add(key string) chan error {
+	ch := make(chan error, 1)
+	w[key] = append(w[key], ch)
+	return ch
+}
+
+func (w waiters) remove(key string, ch chan error) {
+	pending := w[key]
+	for i, waiter := range pending {
+		if waiter == ch {
+			pending = append(pending[:i], pending[i+1:]...)
+			break
+		}
+	}
+	if len(pending) == 0 {
+		delete(w, key)
+	} else {
+		w[key] = pending
+	}
+}
+
+//resolve wakes up every caller waiting on key with err, nil meaning success.
+func (w waiters) resolve(key string, err error) {
+	for _, ch := range w[key] {
+		ch <- err
+	}
+	delete(w, key)
+}
+
+func (w waiters) resolveAll(err error) {
+	for key := range w {
+		w.resolve(key, err)
+	}
+}